@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/cmdpdx/tf-state-import/pkg/execute"
+	"github.com/cmdpdx/tf-state-import/pkg/journal"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+)
+
+func applyCommand(args []string) error {
+	fs := newFlagSet("apply", "[flags]", "Run the `terraform state rm` and `terraform import` steps for every resource in the\nstate file, stopping at the first failure.")
+	var sf stateFlags
+	sf.register(fs)
+	includeRemove := fs.Bool("include-remove", true, "Run `terraform state rm` for each resource before importing it.")
+	rollbackDir := fs.String("rollback-dir", "", "Directory to write a rollback artifact to before running any step. If empty, no rollback artifact is written.")
+	binary := fs.String("binary", "terraform", "Binary used to run steps, e.g. 'terraform' or 'tofu'.")
+	parallelism := fs.Int("parallelism", 1, "Number of imports to run at once. A resource is only imported after all of its dependencies were imported successfully.")
+	lockTimeout := fs.Duration("lock-timeout", 0, "Duration to wait for the state lock, passed to terraform as -lock-timeout.")
+	lockRetries := fs.Int("lock-retries", 3, "Number of times to retry a step that failed to acquire the state lock.")
+	serializeWrites := fs.Bool("serialize-writes", false, "Run one step at a time regardless of -parallelism, for backends that don't support concurrent writers.")
+	journalFile := fs.String("journal", "", "Append a record of every step to this file, so an interrupted migration can be resumed with -resume.")
+	checkConfigDir := fs.String("check-config", "", "Check the resources against the Terraform configuration in this directory, including local modules, and fail before running any step if a resource has no matching resource block.")
+	resume := fs.Bool("resume", false, "Resume the migration recorded in -journal: reconcile it with the current state from `terraform state pull` and run the remaining steps. -tfstate must be the original state, e.g. from -rollback-dir.")
+	if err := sf.parse(fs, args); err != nil {
+		return err
+	}
+	if *resume && *journalFile == "" {
+		return usageErrorf(fs, "-resume requires -journal")
+	}
+
+	loaded, err := sf.load()
+	if err != nil {
+		return err
+	}
+	ordered, err := loaded.ordered()
+	if err != nil {
+		return err
+	}
+	warnDeposed(loaded)
+	for _, m := range excludedMessages(loaded.excluded, sf.instructions()) {
+		log.Println(m)
+	}
+
+	if err := checkConfig(*checkConfigDir, loaded, sf.config.RewriteAddress); err != nil {
+		return err
+	}
+	if err := writeRollback(*rollbackDir, loaded, ordered); err != nil {
+		return err
+	}
+
+	e := execute.Executor{
+		Runner:          execute.BinaryRunner{Binary: *binary},
+		Stdout:          os.Stdout,
+		Stderr:          os.Stderr,
+		LockTimeout:     *lockTimeout,
+		LockRetries:     *lockRetries,
+		SerializeWrites: *serializeWrites,
+	}
+	ctx := context.Background()
+	if sf.workspace != "" {
+		if err := e.Runner.Run(ctx, []string{"workspace", "select", sf.workspace}, os.Stdout, os.Stderr); err != nil {
+			return fmt.Errorf("selecting workspace %s: %w", sf.workspace, err)
+		}
+	}
+	steps, deps := rewriteSteps(execute.Plan(ordered, *includeRemove), loaded.resources.DependencyAddresses(), sf.config.RewriteAddress)
+	return runApply(ctx, &e, steps, deps, *journalFile, *resume, *parallelism)
+}
+
+// rewriteSteps moves the imports and taints, and the dependencies between
+// them, to the addresses given by rewrite. Resources are still removed from
+// their address in state.
+func rewriteSteps(steps []execute.Step, deps map[string][]string, rewrite func(string) string) ([]execute.Step, map[string][]string) {
+	rewritten := make([]execute.Step, len(steps))
+	for i, s := range steps {
+		if s.Action == execute.Import || s.Action == execute.Taint {
+			s.Address = rewrite(s.Address)
+		}
+		rewritten[i] = s
+	}
+	rewrittenDeps := make(map[string][]string, len(deps))
+	for a, ds := range deps {
+		for _, d := range ds {
+			rewrittenDeps[rewrite(a)] = append(rewrittenDeps[rewrite(a)], rewrite(d))
+		}
+	}
+	return rewritten, rewrittenDeps
+}
+
+func runApply(ctx context.Context, e *execute.Executor, steps []execute.Step, deps map[string][]string, journalFile string, resume bool, parallelism int) error {
+	if resume {
+		entries, err := journal.Read(journalFile)
+		if err != nil {
+			return err
+		}
+		current, err := execute.Pull(ctx, e.Runner, os.Stderr)
+		if err != nil {
+			return err
+		}
+		var warnings []string
+		steps, warnings = journal.Reconcile(steps, entries, resources.FromState(current, ""))
+		for _, w := range warnings {
+			log.Println(w)
+		}
+		log.Printf("resuming with %d remaining steps", len(steps))
+	}
+
+	if journalFile != "" {
+		j, err := journal.Open(journalFile)
+		if err != nil {
+			return err
+		}
+		defer j.Close()
+		e.Recorder = j
+	}
+
+	var results []execute.Result
+	var err error
+	if parallelism > 1 {
+		results, err = e.RunParallel(ctx, steps, deps, parallelism)
+	} else {
+		results, err = e.Run(ctx, steps)
+	}
+	execute.Summarize(os.Stderr, steps, results)
+	return err
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cmdpdx/tf-state-import/pkg/execute"
+)
+
+func TestRewriteSteps(t *testing.T) {
+	steps := []execute.Step{
+		{Action: execute.Remove, Address: "module.old.t.b"},
+		{Action: execute.Remove, Address: "module.old.t.a"},
+		{Action: execute.Import, Address: "module.old.t.a", ID: "a"},
+		{Action: execute.Import, Address: "module.old.t.b", ID: "b"},
+		{Action: execute.Taint, Address: "module.old.t.b"},
+	}
+	deps := map[string][]string{"module.old.t.b": {"module.old.t.a"}}
+	rewrite := func(a string) string { return strings.Replace(a, "module.old.", "module.new.", 1) }
+
+	gotSteps, gotDeps := rewriteSteps(steps, deps, rewrite)
+
+	wantSteps := []execute.Step{
+		{Action: execute.Remove, Address: "module.old.t.b"},
+		{Action: execute.Remove, Address: "module.old.t.a"},
+		{Action: execute.Import, Address: "module.new.t.a", ID: "a"},
+		{Action: execute.Import, Address: "module.new.t.b", ID: "b"},
+		{Action: execute.Taint, Address: "module.new.t.b"},
+	}
+	if diff := cmp.Diff(wantSteps, gotSteps); diff != "" {
+		t.Errorf("rewriteSteps() steps mismatch (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string][]string{"module.new.t.b": {"module.new.t.a"}}, gotDeps); diff != "" {
+		t.Errorf("rewriteSteps() dependencies mismatch (-want, +got):\n%s", diff)
+	}
+	if steps[2].Address != "module.old.t.a" {
+		t.Error("rewriteSteps() changed the steps it was given")
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/cmdpdx/tf-state-import/pkg/batch"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+)
+
+// batchCommand generates the statements for many state files at once, writing
+// one output file per state file.
+func batchCommand(args []string) error {
+	fs := newFlagSet("batch", "[flags] DIR|GLOB...", "Generate statements for every state file in the given directories (searched recursively for\n*.tfstate files) or globs, processing them concurrently. Each state file's statements are written\nto its own file in -out-dir, followed by a summary of resource counts, skipped resources and errors.")
+	var cf configFlags
+	cf.register(fs)
+	provider := fs.String("provider", "", "Filter resources by the given provider string, including partial matches. If empty, all resources will be included.")
+	includeRemove := fs.Bool("include-remove", true, "Include `terraform rm` statements to alter state in place.")
+	format := fs.String("format", "command", "How to structure the output, one of 'command' or 'block'. 'block' implies include-remove=false")
+	forEach := fs.Bool("for-each", false, "With -format=block, import each for_each collection with a single for_each import block. Requires Terraform 1.7 or later.")
+	outDir := fs.String("out-dir", "", "Directory to write each state file's statements to, mirroring the layout of the state files.")
+	var pf policyFlags
+	pf.register(fs)
+	parallelism := fs.Int("parallelism", 4, "Number of state files to process at once.")
+	force := fs.Bool("force", false, "Overwrite output files in -out-dir that already exist.")
+	if err := cf.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageErrorf(fs, "expected at least one directory or glob of state files")
+	}
+	if *outDir == "" {
+		return usageErrorf(fs, "-out-dir is required")
+	}
+	if *format != "command" && *format != "block" {
+		return usageErrorf(fs, "unknown format %q", *format)
+	}
+	if *forEach && *format != "block" {
+		return usageErrorf(fs, "-for-each requires -format=block")
+	}
+	if err := pf.validate(fs); err != nil {
+		return err
+	}
+	if *format == "block" {
+		*includeRemove = false
+	}
+
+	stacks, err := batch.Discover(fs.Args()...)
+	if err != nil {
+		return err
+	}
+	if len(stacks) == 0 {
+		return errors.New("no state files found")
+	}
+	log.Printf("processing %d state files", len(stacks))
+
+	ext := ".sh"
+	if *format == "block" {
+		ext = ".tf"
+	}
+	summary := batch.Run(context.Background(), stacks, batch.Options{
+		OutDir:      *outDir,
+		Extension:   ext,
+		Provider:    *provider,
+		Parallelism: *parallelism,
+		Force:       *force,
+		Generate: func(rm resources.ResourceMap, w io.Writer) (int, []resources.Skipped, error) {
+			kept, excluded, err := pf.apply(rm)
+			if err != nil {
+				return 0, nil, err
+			}
+			ordered, err := kept.Order()
+			if err != nil {
+				return 0, nil, err
+			}
+			return len(ordered), leftOut(rm, kept, excluded), output(w, ordered, generateOptions{
+				includeRemove: *includeRemove,
+				format:        *format,
+				rewrite:       cf.config.RewriteAddress,
+				forEach:       *forEach,
+				excluded:      excluded,
+				instructions:  pf.instructions(),
+			})
+		},
+	})
+	if err := summary.Write(os.Stdout); err != nil {
+		return err
+	}
+	if n := summary.Failed(); n > 0 {
+		return fmt.Errorf("%d of %d state files failed", n, len(stacks))
+	}
+	return nil
+}
+
+// leftOut returns the resources of rm that the policies left out of kept, the
+// non-importable ones that are excluded and the tainted ones that are skipped.
+func leftOut(rm, kept resources.ResourceMap, excluded []resources.Excluded) []resources.Skipped {
+	reasons := map[string]string{}
+	for _, e := range excluded {
+		reasons[e.Address] = "left in state, it " + e.Reason
+	}
+	var skipped []resources.Skipped
+	for _, a := range resources.SortedKeys(rm) {
+		if _, ok := kept[a]; ok {
+			continue
+		}
+		reason, ok := reasons[a]
+		if !ok {
+			reason = "tainted, skipped by -tainted=skip"
+		}
+		skipped = append(skipped, resources.Skipped{Address: a, Reason: reason})
+	}
+	return skipped
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/cmdpdx/tf-state-import/pkg/hcl"
+	"github.com/cmdpdx/tf-state-import/pkg/imports"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+)
+
+// cleanupCommand removes the generated import and removed blocks that have
+// been applied from the configuration.
+func cleanupCommand(args []string) error {
+	fs := newFlagSet("cleanup", "[flags]", "Remove the import and removed blocks that have been applied from the files that\ntf-state-import generated, checking them against the state after the apply. Generated\nfiles left with nothing but comments are deleted. Blocks written by hand are kept.")
+	var sf stateFlags
+	sf.register(fs)
+	dir := fs.String("dir", ".", "Directory of the root module to clean up.")
+	dryRun := fs.Bool("dry-run", false, "Only log the blocks that would be removed, without changing any file.")
+	if err := sf.parse(fs, args); err != nil {
+		return err
+	}
+
+	loaded, err := sf.load()
+	if err != nil {
+		return err
+	}
+	root, err := hcl.LoadModule(*dir)
+	if err != nil {
+		return err
+	}
+	all := resources.FromState(loaded.state, "")
+
+	blocks, err := imports.CleanupBlocks(root, all)
+	if err != nil {
+		return err
+	}
+	verb := "removing"
+	if *dryRun {
+		verb = "would remove"
+	}
+	byFile := map[string][]hcl.Range{}
+	for _, b := range blocks {
+		if !b.Remove {
+			log.Printf("keeping %s block for %s at %s, %s", b.Kind, b.Address, b.Pos, b.Reason)
+			continue
+		}
+		log.Printf("%s %s block for %s at %s", verb, b.Kind, b.Address, b.Pos)
+		byFile[b.Pos.File] = append(byFile[b.Pos.File], b.Range)
+	}
+	if len(byFile) == 0 {
+		log.Printf("nothing to clean up in %s", *dir)
+		return nil
+	}
+	if *dryRun {
+		return nil
+	}
+
+	files := make([]string, 0, len(byFile))
+	for f := range byFile {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		if err := removeBlocks(f, byFile[f]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeBlocks rewrites the generated file without the blocks at the ranges,
+// or deletes it if nothing but comments is left.
+func removeBlocks(path string, ranges []hcl.Range) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	out := hcl.RemoveBlocks(src, ranges)
+	if hcl.Blank(out) {
+		log.Printf("deleting %s", path)
+		return os.Remove(path)
+	}
+	if err := os.WriteFile(path, out, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("rewriting %s: %w", path, err)
+	}
+	log.Printf("removed %d blocks from %s", len(ranges), path)
+	return nil
+}
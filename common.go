@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/exp/maps"
+
+	"github.com/cmdpdx/tf-state-import/pkg/config"
+	"github.com/cmdpdx/tf-state-import/pkg/files"
+	"github.com/cmdpdx/tf-state-import/pkg/hcl"
+	"github.com/cmdpdx/tf-state-import/pkg/redact"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+	"github.com/cmdpdx/tf-state-import/pkg/validate"
+	"github.com/cmdpdx/tf-state-import/pkg/workspace"
+)
+
+// stateFlags are the state loading and filtering options shared by commands
+// that work on a single state file.
+type stateFlags struct {
+	configFlags
+	policyFlags
+	tfstate   string
+	provider  string
+	workspace string
+}
+
+func (f *stateFlags) register(fs *flag.FlagSet) {
+	f.configFlags.register(fs)
+	fs.StringVar(&f.tfstate, "tfstate", "terraform.tfstate", "tfstate file to create import statements from. If empty, looks in the current directory for 'terraform.tfstate'. May also be a Terraform HTTP backend address (http://host/state) or an S3 object (s3://bucket/key).")
+	fs.StringVar(&f.workspace, "workspace", "", "Read the state of this local backend workspace, terraform.tfstate.d/NAME/terraform.tfstate next to -tfstate, instead of -tfstate itself.")
+	fs.StringVar(&f.provider, "provider", "", "Filter resources by the given provider string, including partial matches. If empty, all resources will be included.")
+	f.policyFlags.register(fs)
+}
+
+// parse parses the command line, applies the project config to every flag
+// that wasn't given on it and validates the policies.
+func (f *stateFlags) parse(fs *flag.FlagSet, args []string) error {
+	if err := f.configFlags.parse(fs, args); err != nil {
+		return err
+	}
+	return f.policyFlags.validate(fs)
+}
+
+// configFlags is the project config option, shared by every command that
+// reads the config.
+type configFlags struct {
+	configFile string
+
+	// config is the project config applied by parse.
+	config config.Config
+}
+
+func (f *configFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.configFile, "config", "", "Project config file. If empty, looks in the current directory for '.tf-state-import.yaml'. Flags take precedence over the config.")
+}
+
+// parse parses the command line and applies the project config to every flag
+// that wasn't given on it.
+func (f *configFlags) parse(fs *flag.FlagSet, args []string) error {
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	cfg, err := applyConfig(fs, f.configFile)
+	if err != nil {
+		return err
+	}
+	f.config = cfg
+	return nil
+}
+
+// policyFlags are the policies for tainted and non-importable resources,
+// shared by commands that migrate resources.
+type policyFlags struct {
+	tainted string
+	// nonImportable is the strategy for the resource types in the catalog of
+	// non-importable types.
+	nonImportable string
+}
+
+func (f *policyFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.tainted, "tainted", taintedTaint, taintedUsage)
+	fs.StringVar(&f.nonImportable, "non-importable", nonImportableKeep, nonImportableUsage)
+}
+
+// validate fails for policies that don't exist, once the flags are parsed.
+func (f *policyFlags) validate(fs *flag.FlagSet) error {
+	if !validTainted(f.tainted) {
+		return usageErrorf(fs, "unknown -tainted policy %q", f.tainted)
+	}
+	if !validNonImportable(f.nonImportable) {
+		return usageErrorf(fs, "unknown -non-importable strategy %q", f.nonImportable)
+	}
+	return nil
+}
+
+// apply returns the resources to migrate under the policies, and the resources
+// that the catalog of non-importable types leaves in state.
+func (f *policyFlags) apply(rm resources.ResourceMap) (resources.ResourceMap, []resources.Excluded, error) {
+	var excluded []resources.Excluded
+	if f.nonImportable != nonImportableImport {
+		rm, excluded = rm.SplitNonImportable()
+	}
+	rm, err := applyTaintedPolicy(rm, f.tainted)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rm, excluded, nil
+}
+
+// instructions reports whether non-importable resources are explained.
+func (f *policyFlags) instructions() bool {
+	return f.nonImportable == nonImportableInstructions
+}
+
+// The strategies for resource types that can't be imported without losing
+// values, such as random_password or null_resource.
+const (
+	nonImportableKeep         = "keep"
+	nonImportableInstructions = "instructions"
+	nonImportableImport       = "import"
+)
+
+const nonImportableUsage = "What to do with resource types that can't be imported, or lose values when they are, such as random_password or null_resource: 'keep' leaves them in state untouched, 'instructions' also explains how to migrate them without losing their values, and 'import' removes and imports them like any other resource."
+
+func validNonImportable(strategy string) bool {
+	return strategy == nonImportableKeep || strategy == nonImportableInstructions || strategy == nonImportableImport
+}
+
+// excludedMessages returns a message for each resource that the catalog of
+// non-importable types leaves in state, and with instructions, how to migrate
+// it without losing its values.
+func excludedMessages(excluded []resources.Excluded, instructions bool) []string {
+	var messages []string
+	for _, e := range excluded {
+		m := fmt.Sprintf("%s is left in state, it %s.", e.Address, e.Reason)
+		if instructions {
+			m += fmt.Sprintf("\n  To preserve its values, %s.", e.Preserve)
+		}
+		messages = append(messages, m)
+	}
+	return messages
+}
+
+// The policies for tainted resources.
+const (
+	taintedTaint = "taint"
+	taintedSkip  = "skip"
+	taintedFail  = "fail"
+)
+
+const taintedUsage = "What to do with tainted resources: 'taint' imports them and then marks them tainted again with 'terraform taint', 'skip' leaves them out, and 'fail' refuses to migrate a state that has any."
+
+func validTainted(policy string) bool {
+	return policy == taintedTaint || policy == taintedSkip || policy == taintedFail
+}
+
+// applyTaintedPolicy returns the resources to migrate under the policy for
+// tainted resources.
+func applyTaintedPolicy(rm resources.ResourceMap, policy string) (resources.ResourceMap, error) {
+	var tainted []string
+	for a, r := range rm {
+		if r.Tainted {
+			tainted = append(tainted, a)
+		}
+	}
+	if len(tainted) == 0 || policy == taintedTaint {
+		return rm, nil
+	}
+	slices.Sort(tainted)
+	if policy == taintedFail {
+		return nil, fmt.Errorf("tainted resources, replace them first or use -tainted=taint or -tainted=skip: %s", strings.Join(tainted, ", "))
+	}
+	kept := make(resources.ResourceMap, len(rm))
+	for a, r := range rm {
+		if !r.Tainted {
+			kept[a] = r
+		}
+	}
+	for _, a := range tainted {
+		log.Printf("skipping tainted resource %s", a)
+	}
+	return kept, nil
+}
+
+// warnDeposed logs the deposed objects of the loaded state, which can't be
+// imported and are orphaned once their resources are removed from state.
+func warnDeposed(loaded loadedState) {
+	for _, d := range resources.Deposed(loaded.state, loaded.provider) {
+		log.Printf("deposed object %s won't be imported, destroy it outside of Terraform", d)
+	}
+}
+
+// configFlagCommands are the commands that config settings apply to, for flags
+// that mean something else in other commands: the config's format is the format
+// of generated imports, and its parallelism the number of concurrent imports.
+var configFlagCommands = map[string][]string{
+	"format":      {"generate", "batch"},
+	"parallelism": {"apply"},
+}
+
+// applyConfig loads the project config, from filename or discovered in the
+// current directory, sets every flag that wasn't given on the command line
+// and registers its import rules.
+func applyConfig(fs *flag.FlagSet, filename string) (config.Config, error) {
+	if filename == "" {
+		var ok bool
+		if filename, ok = config.Discover("."); !ok {
+			return config.Config{}, nil
+		}
+	}
+	cfg, err := config.Load(filename)
+	if err != nil {
+		return config.Config{}, err
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+	for name, value := range cfg.Flags {
+		// Settings for flags the command doesn't have, e.g. execution options
+		// for generate, don't apply.
+		if set[name] || fs.Lookup(name) == nil {
+			continue
+		}
+		if cmds, ok := configFlagCommands[name]; ok && !slices.Contains(cmds, fs.Name()) {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return config.Config{}, fmt.Errorf("%s: %s: %w", filename, name, err)
+		}
+	}
+
+	rules, err := cfg.ImportRules()
+	if err != nil {
+		return config.Config{}, err
+	}
+	resources.AddRules(rules...)
+	return cfg, nil
+}
+
+// redactFlags is the opt-out of redacting sensitive values, shared by commands
+// that show attributes.
+type redactFlags struct {
+	show bool
+}
+
+func (f *redactFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&f.show, "show-sensitive", false, "Show sensitive attribute values instead of '"+redact.Marker+"'. Values are sensitive if state marks them so, or their attribute name matches a default or 'sensitive_attributes' pattern of the project config.")
+}
+
+// policy returns the redaction policy with the patterns of the project config.
+func (f redactFlags) policy(cfg config.Config) redact.Policy {
+	return redact.Policy{
+		Patterns: append(append([]string(nil), redact.DefaultPatterns...), cfg.Sensitive...),
+		Disabled: f.show,
+	}
+}
+
+// loadedState is a parsed state file and its filtered resources.
+type loadedState struct {
+	location  string
+	raw       []byte
+	state     state.V4
+	resources resources.ResourceMap
+	// provider is the filter the resources were loaded with.
+	provider string
+	// excluded are the resources that the catalog of non-importable types
+	// leaves out of resources.
+	excluded []resources.Excluded
+}
+
+// selectedWorkspace returns the workspace given with -workspace.
+func (f *stateFlags) selectedWorkspace() (workspace.Workspace, error) {
+	return workspace.Find(filepath.Dir(f.tfstate), f.workspace)
+}
+
+func (f *stateFlags) load() (loadedState, error) {
+	if f.workspace == "" {
+		return f.loadFrom(f.tfstate)
+	}
+	ws, err := f.selectedWorkspace()
+	if err != nil {
+		return loadedState{}, err
+	}
+	return f.loadFrom(ws.StateFile)
+}
+
+func (f *stateFlags) loadFrom(location string) (loadedState, error) {
+	raw, st, err := state.Read(context.Background(), location)
+	if err != nil {
+		return loadedState{}, err
+	}
+	rm, excluded, err := f.apply(resources.FromState(st, f.provider))
+	if err != nil {
+		return loadedState{}, fmt.Errorf("%s: %w", location, err)
+	}
+	return loadedState{
+		location:  location,
+		raw:       raw,
+		state:     st,
+		resources: rm,
+		provider:  f.provider,
+		excluded:  excluded,
+	}, nil
+}
+
+// checkConfig checks the loaded resources against the Terraform configuration
+// in dir, logging every problem, and fails if any resource has no matching
+// resource block.
+func checkConfig(dir string, loaded loadedState, rewrite func(string) string) error {
+	if dir == "" {
+		return nil
+	}
+	problems, err := configProblems(dir, loaded, rewrite)
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		log.Println(p)
+	}
+	if validate.HasErrors(problems) {
+		return fmt.Errorf("state doesn't match the configuration in %s", dir)
+	}
+	return nil
+}
+
+func configProblems(dir string, loaded loadedState, rewrite func(string) string) ([]validate.Problem, error) {
+	root, err := hcl.LoadModule(dir)
+	if err != nil {
+		return nil, err
+	}
+	return validate.Config(loaded.resources, resources.FromState(loaded.state, ""), root, rewrite), nil
+}
+
+// ordered returns the loaded resources from least to most dependent.
+func (l loadedState) ordered() ([]*resources.Tuple, error) {
+	return l.resources.Order()
+}
+
+// writeState writes a state file for `terraform state push` to path, which
+// is only overwritten with force.
+func writeState(path string, st state.V4, force bool) error {
+	bs, err := state.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return files.Write(path, bs, 0o600, force)
+}
+
+// listFlag is a flag that can be given more than once.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// pairsFlag is a flag of FROM=TO pairs that can be given more than once.
+type pairsFlag map[string]string
+
+func (p pairsFlag) String() string {
+	var pairs []string
+	for _, from := range maps.Keys(p) {
+		pairs = append(pairs, from+"="+p[from])
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (p pairsFlag) Set(s string) error {
+	from, to, ok := strings.Cut(s, "=")
+	if !ok || from == "" || to == "" {
+		return fmt.Errorf("expected FROM=TO, got %q", s)
+	}
+	p[from] = to
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/exp/maps"
+
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+)
+
+func TestApplyConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".tf-state-import.yaml")
+	cfg := "format: block\ntainted: skip\nexecution:\n  parallelism: 4\n"
+	if err := os.WriteFile(path, []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		command string
+		args    []string
+		want    map[string]string
+	}{{
+		name:    "config sets defaults",
+		command: "generate",
+		want:    map[string]string{"format": "block", "tainted": "skip", "parallelism": "1"},
+	}, {
+		name:    "flags override the config",
+		command: "generate",
+		args:    []string{"-tainted", "fail", "-format", "command"},
+		want:    map[string]string{"format": "command", "tainted": "fail", "parallelism": "1"},
+	}, {
+		name:    "format and parallelism only apply to their commands",
+		command: "apply",
+		want:    map[string]string{"format": "command", "tainted": "skip", "parallelism": "4"},
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet(tt.command, flag.ContinueOnError)
+			fs.String("format", "command", "")
+			fs.String("tainted", taintedTaint, "")
+			fs.Int("parallelism", 1, "")
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := applyConfig(fs, path); err != nil {
+				t.Fatalf("applyConfig() = %v", err)
+			}
+			got := map[string]string{}
+			fs.VisitAll(func(f *flag.Flag) { got[f.Name] = f.Value.String() })
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("flags after applyConfig() mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := applyConfig(flag.NewFlagSet("generate", flag.ContinueOnError), filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("applyConfig() of a missing config file succeeded")
+	}
+}
+
+func TestApplyTaintedPolicy(t *testing.T) {
+	quiet(t)
+	rm := resources.ResourceMap{
+		"t.a": {Type: "t", Name: "a", ID: "a"},
+		"t.b": {Type: "t", Name: "b", ID: "b", Tainted: true},
+	}
+	for _, tt := range []struct {
+		policy  string
+		want    []string
+		wantErr bool
+	}{
+		{policy: taintedTaint, want: []string{"t.a", "t.b"}},
+		{policy: taintedSkip, want: []string{"t.a"}},
+		{policy: taintedFail, wantErr: true},
+	} {
+		t.Run(tt.policy, func(t *testing.T) {
+			got, err := applyTaintedPolicy(rm, tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyTaintedPolicy() error = %v, want error %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			addresses := maps.Keys(got)
+			slices.Sort(addresses)
+			if diff := cmp.Diff(tt.want, addresses); diff != "" {
+				t.Errorf("applyTaintedPolicy() mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+
+	none := resources.ResourceMap{"t.a": rm["t.a"]}
+	if got, err := applyTaintedPolicy(none, taintedFail); err != nil || len(got) != 1 {
+		t.Errorf("applyTaintedPolicy() without tainted resources = %v, %v, want them all", got, err)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/cmdpdx/tf-state-import/pkg/config"
+)
+
+func configCommand(args []string) error {
+	fs := newFlagSet("config", "validate [flags]", "Check the project config file for unknown settings and invalid values.")
+	configFile := fs.String("config", "", "Project config file. If empty, looks in the current directory for '.tf-state-import.yaml'.")
+	if len(args) == 0 || args[0] != "validate" {
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "-help" || args[0] == "--help") {
+			fs.Usage()
+			return nil
+		}
+		return usageErrorf(fs, "expected the 'validate' subcommand")
+	}
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	path := *configFile
+	if path == "" {
+		var ok bool
+		if path, ok = config.Discover("."); !ok {
+			return fmt.Errorf("no config file found, looked for %v", config.Filenames)
+		}
+	}
+	if _, err := config.Load(path); err != nil {
+		return err
+	}
+	fmt.Printf("ok: %s\n", path)
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/cmdpdx/tf-state-import/pkg/diff"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+)
+
+// diffCommand compares two state files, e.g. before and after a provider
+// upgrade or two workspaces.
+func diffCommand(args []string) error {
+	fs := newFlagSet("diff", "[flags] OLD.tfstate NEW.tfstate", "Compare two state files, e.g. before and after a provider upgrade or two workspaces,\nand report added, removed, and changed resources and attributes.")
+	var cf configFlags
+	cf.register(fs)
+	provider := fs.String("provider", "", "Filter resources by the given provider string, including partial matches. If empty, all resources will be compared.")
+	format := fs.String("format", "text", "Output format, one of 'text' or 'json'.")
+	var rf redactFlags
+	rf.register(fs)
+	if err := cf.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return usageErrorf(fs, "expected two state files, got %d", fs.NArg())
+	}
+
+	_, before, err := state.Read(context.Background(), fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	_, after, err := state.Read(context.Background(), fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	result := diff.States(resources.FromState(before, *provider), resources.FromState(after, *provider), rf.policy(cf.config))
+	switch *format {
+	case "json":
+		return result.WriteJSON(os.Stdout)
+	case "text":
+		return result.WriteText(os.Stdout)
+	default:
+		return usageErrorf(fs, "unknown format %q", *format)
+	}
+}
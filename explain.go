@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+
+	"github.com/cmdpdx/tf-state-import/pkg/explain"
+)
+
+func explainCommand(args []string) error {
+	fs := newFlagSet("explain", "[flags] ADDRESS", "Explain how the import of the resource at ADDRESS is generated: its parsed address and\nprovider, the rule and attributes that produced its import ID, its dependencies and\ndependents, and its position in the plan.")
+	var sf stateFlags
+	sf.register(fs)
+	var rf redactFlags
+	rf.register(fs)
+	if err := sf.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageErrorf(fs, "expected a single resource address, got %d arguments", fs.NArg())
+	}
+
+	loaded, err := sf.load()
+	if err != nil {
+		return err
+	}
+	e, err := explain.Explain(loaded.state, loaded.resources, fs.Arg(0), rf.policy(sf.config))
+	if err != nil {
+		return err
+	}
+	return e.Write(os.Stdout)
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/exp/maps"
+
+	"github.com/cmdpdx/tf-state-import/pkg/files"
+	"github.com/cmdpdx/tf-state-import/pkg/hcl"
+	"github.com/cmdpdx/tf-state-import/pkg/imports"
+	"github.com/cmdpdx/tf-state-import/pkg/redact"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+	"github.com/cmdpdx/tf-state-import/pkg/rollback"
+	"github.com/cmdpdx/tf-state-import/pkg/workspace"
+)
+
+func generateCommand(args []string) error {
+	fs := newFlagSet("generate", "[flags]", "Print `terraform state rm` and `terraform import` statements, or import blocks, that\nre-import every resource in the state file in dependency order.")
+	var sf stateFlags
+	sf.register(fs)
+	includeRemove := fs.Bool("include-remove", true, "Include `terraform rm` statements to alter state in place.")
+	format := fs.String("format", "command", "How to structure the output, one of 'command' or 'block'. 'block' implies include-remove=false")
+	rollbackDir := fs.String("rollback-dir", "", "Directory to write a rollback artifact to: a copy of the original state, a script that pushes it back with `terraform state push -force`, and the list of touched resources. If empty, no rollback artifact is written. With -all-workspaces, each workspace gets a subdirectory.")
+	outDir := fs.String("out-dir", "", "Write the import blocks to imports.tf in this directory instead of stdout. Requires -format=block.")
+	splitModules := fs.Bool("split-modules", false, "With -out-dir, write the import blocks of resources in modules to imports_<module>.tf.")
+	generateConfig := fs.String("generate-config", "", "Write a best-effort resource block for each imported resource in the root module to this file, using the attributes from state. An offline alternative to 'terraform plan -generate-config-out'.")
+	force := fs.Bool("force", false, "With -out-dir or -generate-config, overwrite existing files.")
+	forEach := fs.Bool("for-each", false, "With -format=block, import each for_each collection with a single for_each import block. Requires Terraform 1.7 or later.")
+	skipExisting := fs.String("skip-existing", "", "With -format=block, skip the resources that an import block in the Terraform configuration in this directory already imports, and fail if one imports a different ID.")
+	checkConfigDir := fs.String("check-config", "", "Check the resources against the Terraform configuration in this directory, including local modules, and fail before producing any output if a resource has no matching resource block.")
+	var rf redactFlags
+	rf.register(fs)
+	allWorkspaces := fs.Bool("all-workspaces", false, "Generate statements for every local backend workspace next to -tfstate, each preceded by a 'terraform workspace select' statement.")
+	if err := sf.parse(fs, args); err != nil {
+		return err
+	}
+	if *format != "command" && *format != "block" {
+		return usageErrorf(fs, "unknown format %q", *format)
+	}
+
+	if *outDir != "" && *format != "block" {
+		return usageErrorf(fs, "-out-dir requires -format=block")
+	}
+	if *forEach && *format != "block" {
+		return usageErrorf(fs, "-for-each requires -format=block")
+	}
+	if *skipExisting != "" && *format != "block" {
+		return usageErrorf(fs, "-skip-existing requires -format=block")
+	}
+	if *splitModules && *outDir == "" {
+		return usageErrorf(fs, "-split-modules requires -out-dir")
+	}
+
+	if *allWorkspaces {
+		if sf.workspace != "" {
+			return usageErrorf(fs, "-workspace and -all-workspaces are mutually exclusive")
+		}
+		if *format == "block" {
+			return usageErrorf(fs, "import blocks import into the selected workspace, use -workspace with -format=block")
+		}
+	}
+
+	if *format == "block" && *includeRemove {
+		log.Println("format=block implies includeRemove=false...")
+		*includeRemove = false
+	}
+
+	opts := generateOptions{
+		rollbackDir:   *rollbackDir,
+		includeRemove: *includeRemove,
+		format:        *format,
+		rewrite:       sf.config.RewriteAddress,
+		outDir:        *outDir,
+		splitModules:  *splitModules,
+		force:         *force,
+		forEach:       *forEach,
+		configOut:     *generateConfig,
+		checkConfig:   *checkConfigDir,
+		skipExisting:  *skipExisting,
+		redact:        rf.policy(sf.config),
+		instructions:  sf.instructions(),
+	}
+	if !*allWorkspaces && sf.workspace == "" {
+		loaded, err := sf.load()
+		if err != nil {
+			return err
+		}
+		_, err = generate(os.Stdout, loaded, opts)
+		return err
+	}
+
+	var workspaces []workspace.Workspace
+	if *allWorkspaces {
+		var err error
+		if workspaces, err = workspace.Discover(filepath.Dir(sf.tfstate)); err != nil {
+			return err
+		}
+		if len(workspaces) == 0 {
+			return fmt.Errorf("no workspaces with state found in %s", filepath.Dir(sf.tfstate))
+		}
+	} else {
+		ws, err := sf.selectedWorkspace()
+		if err != nil {
+			return err
+		}
+		workspaces = []workspace.Workspace{ws}
+	}
+
+	counts := make([]int, len(workspaces))
+	for i, ws := range workspaces {
+		loaded, err := sf.loadFrom(ws.StateFile)
+		if err != nil {
+			return fmt.Errorf("workspace %s: %w", ws.Name, err)
+		}
+		wsOpts := opts
+		if opts.rollbackDir != "" && *allWorkspaces {
+			wsOpts.rollbackDir = filepath.Join(opts.rollbackDir, ws.Name)
+		}
+		// Import blocks have no workspace, the user selects it before planning.
+		if *format == "command" {
+			if _, err := fmt.Fprintln(os.Stdout, ws.SelectCommand()); err != nil {
+				return err
+			}
+		}
+		if counts[i], err = generate(os.Stdout, loaded, wsOpts); err != nil {
+			return fmt.Errorf("workspace %s: %w", ws.Name, err)
+		}
+	}
+
+	total := 0
+	for i, ws := range workspaces {
+		log.Printf("workspace %s: %d resources", ws.Name, counts[i])
+		total += counts[i]
+	}
+	if len(workspaces) > 1 {
+		log.Printf("%d workspaces: %d resources", len(workspaces), total)
+	}
+	return nil
+}
+
+type generateOptions struct {
+	rollbackDir   string
+	includeRemove bool
+	format        string
+	rewrite       func(string) string
+	outDir        string
+	splitModules  bool
+	force         bool
+	forEach       bool
+	configOut     string
+	checkConfig   string
+	skipExisting  string
+	redact        redact.Policy
+	// excluded are the resources left in state by the catalog of
+	// non-importable types, and instructions whether to explain how to
+	// migrate them.
+	excluded     []resources.Excluded
+	instructions bool
+}
+
+// generate writes the statements for a loaded state, to out or to files in
+// outDir, and returns the number of resources they import.
+func generate(out io.Writer, loaded loadedState, opts generateOptions) (int, error) {
+	ordered, err := loaded.ordered()
+	if err != nil {
+		return 0, err
+	}
+	warnDeposed(loaded)
+	opts.excluded = loaded.excluded
+	if err := checkConfig(opts.checkConfig, loaded, opts.rewrite); err != nil {
+		return 0, err
+	}
+	if err := writeRollback(opts.rollbackDir, loaded, ordered); err != nil {
+		return 0, err
+	}
+	if opts.configOut != "" {
+		if err := writeConfig(loaded, ordered, opts); err != nil {
+			return 0, err
+		}
+	}
+	if opts.outDir != "" {
+		return len(ordered), writeImportFiles(loaded, ordered, opts)
+	}
+	return len(ordered), output(out, ordered, opts)
+}
+
+// writeImportFiles writes the import blocks to imports.tf, or a file per
+// module, in opts.outDir.
+func writeImportFiles(loaded loadedState, ordered []*resources.Tuple, opts generateOptions) error {
+	blocks, err := importBlocks(ordered, opts)
+	if err != nil {
+		return err
+	}
+	files, err := imports.Files(blocks, opts.splitModules)
+	if err != nil {
+		return err
+	}
+	header := imports.GeneratedHeader + " from " + loaded.location
+	if loaded.state.Lineage != "" {
+		header += fmt.Sprintf("\n(lineage %s, serial %d)", loaded.state.Lineage, loaded.state.Serial)
+	}
+	header += ".\n\nImport blocks are only allowed in the root module. Review the plan, apply\nit, and then remove these files with 'tf-state-import cleanup'."
+	paths, err := imports.Write(opts.outDir, files, header, opts.force)
+	if err != nil {
+		return err
+	}
+	for i, p := range paths {
+		log.Printf("wrote %d import blocks to %s", len(files[i].Blocks), p)
+	}
+	for _, t := range taints(ordered, opts) {
+		log.Printf("after applying, run: %s", t)
+	}
+	for _, m := range excludedMessages(opts.excluded, opts.instructions) {
+		log.Println(m)
+	}
+	return nil
+}
+
+// taints returns the statements that mark the tainted resources tainted again
+// once they're imported.
+func taints(ordered []*resources.Tuple, opts generateOptions) []string {
+	var statements []string
+	for _, r := range ordered {
+		if r.Tainted {
+			statements = append(statements, fmt.Sprintf("terraform taint '%s'", opts.rewrite(r.Address())))
+		}
+	}
+	return statements
+}
+
+// importBlocks returns the import blocks for the resources, without those that
+// the configuration in opts.skipExisting already has.
+func importBlocks(ordered []*resources.Tuple, opts generateOptions) ([]imports.Block, error) {
+	blocks := imports.Blocks(ordered, opts.forEach, opts.rewrite)
+	if opts.skipExisting == "" {
+		return blocks, nil
+	}
+	root, err := hcl.LoadModule(opts.skipExisting)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMoved(blocks, root, opts.skipExisting); err != nil {
+		return nil, err
+	}
+	var existing []hcl.ImportBlock
+	for _, b := range root.Imports {
+		// Files that are about to be overwritten don't keep their blocks.
+		if !opts.force || opts.outDir == "" || !generatedFile(opts.outDir, b.Pos.File) {
+			existing = append(existing, b)
+		}
+	}
+
+	blocks, duplicates, conflicts := imports.SkipExisting(blocks, existing)
+	for _, d := range duplicates {
+		log.Printf("skipping %s", d)
+	}
+	for _, c := range conflicts {
+		log.Println(c)
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("%d import blocks in %s import a different ID than state", len(conflicts), opts.skipExisting)
+	}
+	return blocks, nil
+}
+
+// checkMoved fails if moved blocks in the configuration move any of the
+// resources the blocks import, which have to be imported at their new address.
+func checkMoved(blocks []imports.Block, root *hcl.Module, dir string) error {
+	moved := 0
+	for _, b := range blocks {
+		addresses := []string{b.To}
+		if b.ForEach != nil {
+			addresses = nil
+			for _, k := range maps.Keys(b.ForEach) {
+				addresses = append(addresses, resources.InstanceAddress(b.To, k))
+			}
+			slices.Sort(addresses)
+		}
+		for _, a := range addresses {
+			if to, m, ok := root.MovedTo(a); ok {
+				log.Printf("%s is moved to %s by the moved block at %s", a, to, m.Pos)
+				moved++
+			}
+		}
+	}
+	if moved > 0 {
+		return fmt.Errorf("moved blocks in %s move %d resources, import them at their new address with a rewrite", dir, moved)
+	}
+	return nil
+}
+
+// generatedFile reports whether path is one of the import files that -out-dir
+// writes to dir.
+func generatedFile(dir, path string) bool {
+	if filepath.Clean(filepath.Dir(path)) != filepath.Clean(dir) {
+		return false
+	}
+	base := filepath.Base(path)
+	return base == imports.RootFile || strings.HasPrefix(base, "imports_") && strings.HasSuffix(base, ".tf")
+}
+
+// writeConfig writes the generated resource configuration to opts.configOut.
+func writeConfig(loaded loadedState, ordered []*resources.Tuple, opts generateOptions) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s from %s.\n#\n", imports.GeneratedHeader, loaded.location)
+	b.WriteString("# Best-effort configuration from the attributes in state. Review every\n")
+	b.WriteString("# resource and run `terraform plan` before relying on it.\n\n")
+	n, err := hcl.WriteResources(&b, ordered, opts.rewrite, opts.redact)
+	if err != nil {
+		return err
+	}
+
+	if err := files.Write(opts.configOut, b.Bytes(), 0o644, opts.force); err != nil {
+		return err
+	}
+	log.Printf("wrote %d resource blocks to %s", n, opts.configOut)
+	return nil
+}
+
+func writeRollback(dir string, loaded loadedState, ordered []*resources.Tuple) error {
+	if dir == "" {
+		return nil
+	}
+	if err := rollback.Write(dir, loaded.raw, loaded.state, ordered); err != nil {
+		return err
+	}
+	log.Printf("wrote rollback artifact to %s", dir)
+	return nil
+}
+
+// output writes the statements that remove the resources from state and
+// import them again, at the address given by opts.rewrite.
+func output(out io.Writer, resources []*resources.Tuple, opts generateOptions) error {
+	var removes []string
+	if opts.includeRemove {
+		removes = make([]string, len(resources))
+		for i, r := range resources {
+			removes[len(removes)-1-i] = fmt.Sprintf("terraform state rm '%s'", r.Address())
+		}
+		_, err := out.Write([]byte(strings.Join(removes, "\n") + "\n"))
+		if err != nil {
+			return err
+		}
+	}
+
+	var statements []string
+	switch opts.format {
+	case "block":
+		blocks, err := importBlocks(resources, opts)
+		if err != nil {
+			return err
+		}
+		for _, b := range blocks {
+			statements = append(statements, b.String())
+		}
+		// Import blocks can't taint, the statements run after applying.
+		for _, t := range taints(resources, opts) {
+			statements = append(statements, "# after applying: "+t)
+		}
+	default:
+		for _, r := range resources {
+			statements = append(statements, fmt.Sprintf("terraform import '%s' %s", opts.rewrite(r.Address()), r.ImportableID()))
+		}
+		statements = append(statements, taints(resources, opts)...)
+	}
+	for _, m := range excludedMessages(opts.excluded, opts.instructions) {
+		statements = append(statements, "# "+strings.ReplaceAll(m, "\n", "\n# "))
+	}
+	_, err := out.Write([]byte(strings.Join(statements, "\n") + "\n"))
+	return err
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cmdpdx/tf-state-import/pkg/hcl"
+	"github.com/cmdpdx/tf-state-import/pkg/imports"
+)
+
+func TestGeneratedFile(t *testing.T) {
+	dir := filepath.Join("infra", "imports")
+	for _, tt := range []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(dir, "imports.tf"), true},
+		{filepath.Join(dir, "imports_network.tf"), true},
+		{filepath.Join("infra", "imports", ".", "imports_a.b.tf"), true},
+		{filepath.Join(dir, "main.tf"), false},
+		{filepath.Join(dir, "imports_network.tf.bak"), false},
+		{filepath.Join(dir, "sub", "imports.tf"), false},
+		{filepath.Join("infra", "imports.tf"), false},
+	} {
+		if got := generatedFile(dir, tt.path); got != tt.want {
+			t.Errorf("generatedFile(%q, %q) = %t, want %t", dir, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCheckMoved(t *testing.T) {
+	quiet(t)
+	dir := t.TempDir()
+	src := "moved {\n  from = t.old\n  to   = t.new\n}\n\nmoved {\n  from = t.keyed[\"a\"]\n  to   = t.other[\"a\"]\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root, err := hcl.LoadModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		blocks  []imports.Block
+		wantErr bool
+	}{
+		{"not moved", []imports.Block{{To: "t.kept", ID: "k"}}, false},
+		{"moved", []imports.Block{{To: "t.kept", ID: "k"}, {To: "t.old", ID: "o"}}, true},
+		{"for_each instance moved", []imports.Block{{To: "t.keyed", ForEach: map[string]string{"a": "a", "b": "b"}}}, true},
+		{"for_each instances not moved", []imports.Block{{To: "t.keyed", ForEach: map[string]string{"b": "b"}}}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkMoved(tt.blocks, root, dir); (err != nil) != tt.wantErr {
+				t.Errorf("checkMoved() = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,7 +1,21 @@
 module github.com/cmdpdx/tf-state-import
 
 go 1.21.1
+
 require (
 	github.com/google/go-cmp v0.7.0
+	github.com/hashicorp/hcl/v2 v2.20.1
+	github.com/zclconf/go-cty v1.13.0
 	golang.org/x/exp v0.0.0-20240112132812-db7319d0e0e3
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/agext/levenshtein v1.2.1 // indirect
+	github.com/apparentlymart/go-textseg/v13 v13.0.0 // indirect
+	github.com/apparentlymart/go-textseg/v15 v15.0.0 // indirect
+	github.com/mitchellh/go-wordwrap v0.0.0-20150314170334-ad45545899c7 // indirect
+	golang.org/x/mod v0.14.0 // indirect
+	golang.org/x/text v0.11.0 // indirect
+	golang.org/x/tools v0.17.0 // indirect
 )
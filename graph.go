@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"golang.org/x/exp/maps"
+)
+
+func graphCommand(args []string) error {
+	fs := newFlagSet("graph", "[flags]", "Print the dependencies between the resources in the state file as a DOT graph. Edges\npoint from a resource to the resources it depends on.")
+	var sf stateFlags
+	sf.register(fs)
+	if err := sf.parse(fs, args); err != nil {
+		return err
+	}
+
+	loaded, err := sf.load()
+	if err != nil {
+		return err
+	}
+	return writeGraph(os.Stdout, loaded.resources.DependencyAddresses(), maps.Keys(loaded.resources))
+}
+
+func writeGraph(w io.Writer, deps map[string][]string, addresses []string) error {
+	sort.Strings(addresses)
+	if _, err := fmt.Fprintln(w, "digraph resources {"); err != nil {
+		return err
+	}
+	for _, a := range addresses {
+		if len(deps[a]) == 0 {
+			if _, err := fmt.Fprintf(w, "  %q;\n", a); err != nil {
+				return err
+			}
+		}
+		for _, d := range deps[a] {
+			if _, err := fmt.Fprintf(w, "  %q -> %q;\n", a, d); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
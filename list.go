@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"golang.org/x/exp/maps"
+)
+
+// listedResource is the JSON form of a resource printed by list.
+type listedResource struct {
+	Address      string                 `json:"address"`
+	Module       string                 `json:"module,omitempty"`
+	Type         string                 `json:"type"`
+	Name         string                 `json:"name"`
+	ID           string                 `json:"id"`
+	ImportID     string                 `json:"import_id"`
+	Dependencies []string               `json:"dependencies,omitempty"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+}
+
+func listCommand(args []string) error {
+	fs := newFlagSet("list", "[flags]", "List the resources in the state file that would be imported, with their import IDs.")
+	var sf stateFlags
+	sf.register(fs)
+	format := fs.String("format", "text", "Output format, one of 'text' or 'json'.")
+	attributes := fs.Bool("attributes", false, "Include resource attributes in json output.")
+	var rf redactFlags
+	rf.register(fs)
+	if err := sf.parse(fs, args); err != nil {
+		return err
+	}
+
+	loaded, err := sf.load()
+	if err != nil {
+		return err
+	}
+	policy := rf.policy(sf.config)
+	addresses := maps.Keys(loaded.resources)
+	sort.Strings(addresses)
+
+	switch *format {
+	case "text":
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, a := range addresses {
+			fmt.Fprintf(w, "%s\t%s\n", a, loaded.resources[a].ImportableID())
+		}
+		return w.Flush()
+	case "json":
+		listed := make([]listedResource, 0, len(addresses))
+		for _, a := range addresses {
+			r := loaded.resources[a]
+			l := listedResource{
+				Address:      a,
+				Module:       r.Module,
+				Type:         r.Type,
+				Name:         r.Name,
+				ID:           r.ID,
+				ImportID:     r.ImportableID(),
+				Dependencies: r.Dependencies,
+			}
+			if *attributes {
+				l.Attributes = policy.Attributes(r.Attributes, r.Sensitive)
+			}
+			listed = append(listed, l)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(listed)
+	default:
+		return usageErrorf(fs, "unknown format %q", *format)
+	}
+}
@@ -1,74 +1,142 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"strings"
+)
 
-	"github.com/cmdpdx/tf-state-import/pkg/resources"
-	"github.com/cmdpdx/tf-state-import/pkg/state"
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
 )
 
-func main() {
-	tfstate := flag.String("tfstate", "terraform.tfstate", "tfstate file to create import statements from. If empty, looks in the current directory for 'terraform.tfstate'")
-	includeRemove := flag.Bool("include-remove", true, "Include `terraform rm` statements to alter state in place.")
-	provider := flag.String("provider", "", "Filter resources by the given provider string, including partial matches. If empty, all resources will be included.")
-	format := flag.String("format", "command", "How to structure the output, one of 'command' or 'block'. 'block' implies include-remove=false")
-	flag.Parse()
+// errUsage is returned for invalid command lines, after the usage has already
+// been printed.
+var errUsage = errors.New("invalid usage")
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
 
-	if *format == "block" && *includeRemove {
-		log.Println("format=block implies includeRemove=false...")
-		*includeRemove = false
+func commands() []command {
+	return []command{
+		{"generate", "Print `state rm` and `import` statements for the resources in a state file (default)", generateCommand},
+		{"batch", "Generate statements for many state files at once", batchCommand},
+		{"apply", "Run the `state rm` and `import` steps directly", applyCommand},
+		{"list", "List the importable resources in a state file", listCommand},
+		{"graph", "Print the resource dependency graph in DOT format", graphCommand},
+		{"explain", "Explain how the import of a single resource is generated", explainCommand},
+		{"validate", "Check that a state file can be migrated", validateCommand},
+		{"verify", "Compare the state after a migration to the original", verifyCommand},
+		{"diff", "Compare two state files", diffCommand},
+		{"rewrite", "Rewrite provider addresses, types or attributes in a copy of the state", rewriteCommand},
+		{"replace-provider", "Replace a provider address in a copy of the state", replaceProviderCommand},
+		{"split", "Move resources out of a state into a new state", splitCommand},
+		{"merge", "Combine several states into one", mergeCommand},
+		{"cleanup", "Remove applied import and removed blocks from the configuration", cleanupCommand},
+		{"config", "Validate the project config file", configCommand},
+		{"help", "Show help for a command", helpCommand},
 	}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
 
-	st, err := state.ParseStateFile(*tfstate)
-	if err != nil {
-		log.Fatal(err)
+// run runs the command named by the first argument and returns the exit code.
+// Without a command, or when the first argument is a flag, it runs generate.
+func run(args []string) int {
+	name := "generate"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
 
-	rm := resources.FromState(st, *provider)
-	ordered := rm.Order()
+	cmd, ok := findCommand(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage(os.Stderr)
+		return exitUsage
+	}
 
-	err = output(os.Stdout, ordered, *includeRemove, *format)
-	if err != nil {
-		log.Fatal(err)
+	err := cmd.run(args)
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	default:
+		fmt.Fprintf(os.Stderr, "tf-state-import %s: %v\n", name, err)
+		return exitError
 	}
 }
 
-func output(out io.Writer, resources []*resources.Tuple, includeRemove bool, format string) error {
-	var removes []string
-	if includeRemove {
-		removes = make([]string, len(resources))
-		for i, r := range resources {
-			removes[len(removes)-1-i] = fmt.Sprintf("terraform state rm '%s'", r.Address())
-		}
-		_, err := out.Write([]byte(strings.Join(removes, "\n") + "\n"))
-		if err != nil {
-			return err
+func findCommand(name string) (command, bool) {
+	for _, c := range commands() {
+		if c.name == name {
+			return c, true
 		}
 	}
+	return command{}, false
+}
 
-	imports := make([]string, len(resources))
-	for i, r := range resources {
-		imports[i] = generateImport(r.Address(), r.ImportableID(), format)
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: tf-state-import <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands() {
+		fmt.Fprintf(w, "  %-16s %s\n", c.name, c.summary)
 	}
-	_, err := out.Write([]byte(strings.Join(imports, "\n") + "\n"))
-	return err
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'tf-state-import help <command>' for the flags of a command.")
 }
 
-const importBlock = `import {
-  to = %s
-  id = "%s"
-}`
+func helpCommand(args []string) error {
+	if len(args) == 0 {
+		usage(os.Stdout)
+		return nil
+	}
+	cmd, ok := findCommand(args[0])
+	if !ok || cmd.name == "help" {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+		usage(os.Stderr)
+		return errUsage
+	}
+	return cmd.run([]string{"-h"})
+}
 
-func generateImport(address, id, format string) string {
-	switch format {
-	case "block":
-		return fmt.Sprintf(importBlock, address, id)
-	default:
-		return fmt.Sprintf("terraform import '%s' %s", address, id)
+// newFlagSet returns a flag set for the named command whose usage shows the
+// given argument synopsis and description.
+func newFlagSet(name, synopsis, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: tf-state-import %s %s\n\n%s\n\nFlags:\n", name, synopsis, description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses args, turning parse failures, which the flag package has
+// already reported, into errUsage.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
 	}
+	return nil
+}
+
+// usageErrorf reports an invalid command line along with the command's usage.
+func usageErrorf(fs *flag.FlagSet, format string, args ...interface{}) error {
+	fmt.Fprintf(fs.Output(), format+"\n\n", args...)
+	fs.Usage()
+	return errUsage
 }
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+)
+
+// quiet discards what the commands print for the rest of the test.
+func quiet(t *testing.T) {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = devNull, devNull
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		log.SetOutput(stderr)
+		devNull.Close()
+	})
+}
+
+func TestRun(t *testing.T) {
+	quiet(t)
+	for _, tt := range []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"generate by default", []string{"-tfstate", "pkg/state/testdata/example.tfstate"}, exitOK},
+		{"named command", []string{"list", "-tfstate", "pkg/state/testdata/example.tfstate"}, exitOK},
+		{"help", []string{"help"}, exitOK},
+		{"help for a command", []string{"help", "list"}, exitOK},
+		{"-h", []string{"list", "-h"}, exitOK},
+		{"-h for the default command", []string{"-h"}, exitOK},
+		{"failure", []string{"list", "-tfstate", "missing.tfstate"}, exitError},
+		{"failure of the default command", []string{"-tfstate", "missing.tfstate"}, exitError},
+		{"unknown command", []string{"nope"}, exitUsage},
+		{"help for an unknown command", []string{"help", "nope"}, exitUsage},
+		{"unknown flag", []string{"list", "-nope"}, exitUsage},
+		{"invalid flag value", []string{"list", "-tainted", "nope"}, exitUsage},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := run(tt.args); got != tt.want {
+				t.Errorf("run(%q) = %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+// stdout returns what f prints to standard output.
+func stdout(t *testing.T, f func() error) (string, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := os.Stdout
+	os.Stdout = w
+	ferr := f()
+	os.Stdout = orig
+	w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out), ferr
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+	"github.com/cmdpdx/tf-state-import/pkg/transform"
+)
+
+// mergeCommand combines several states into one.
+func mergeCommand(args []string) error {
+	fs := newFlagSet("merge", "[flags] STATE[=MODULE]...", "Combine state files into one, for consolidating stacks. Each STATE can be moved into a\nmodule, as in 'network.tfstate=module.network'. Writes a state with a new lineage to -out,\nor prints the 'terraform import' statements for the target stack with -plan.")
+	var cf configFlags
+	cf.register(fs)
+	out := fs.String("out", "", "File to write the merged state to.")
+	force := fs.Bool("force", false, "Overwrite -out if it exists.")
+	plan := fs.Bool("plan", false, "Print the statements that import every resource of the states into the target stack.")
+	format := fs.String("format", "command", "With -plan, how to structure the statements, one of 'command' or 'block'.")
+	allowDuplicates := fs.Bool("allow-duplicate-ids", false, "Merge even if more than one state manages the same object, found by import ID.")
+	var pf policyFlags
+	pf.register(fs)
+	if err := cf.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		return usageErrorf(fs, "expected at least two state files, got %d", fs.NArg())
+	}
+	if *out == "" && !*plan {
+		return usageErrorf(fs, "expected -out or -plan")
+	}
+	if *format != "command" && *format != "block" {
+		return usageErrorf(fs, "unknown format %q", *format)
+	}
+	if err := pf.validate(fs); err != nil {
+		return err
+	}
+
+	var parts []transform.Part
+	for _, arg := range fs.Args() {
+		location, module := arg, ""
+		if i := strings.LastIndex(arg, "="); i >= 0 && strings.HasPrefix(arg[i+1:], "module.") {
+			location, module = arg[:i], arg[i+1:]
+		}
+		_, st, err := state.Read(context.Background(), location)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, transform.Part{Name: location, State: st, Module: module})
+	}
+
+	merged, duplicates, err := transform.Merge(parts)
+	if err != nil {
+		return err
+	}
+	for _, d := range duplicates {
+		log.Println(d)
+	}
+	if len(duplicates) > 0 && !*allowDuplicates {
+		return fmt.Errorf("%d objects are managed by more than one state, use -allow-duplicate-ids to merge anyway", len(duplicates))
+	}
+	log.Printf("merged %d resources from %d states", len(merged.Resources), len(parts))
+
+	if *out != "" {
+		if err := writeState(*out, merged, *force); err != nil {
+			return err
+		}
+		log.Printf("wrote %s (lineage %s), push it to the new stack with 'terraform state push'", *out, merged.Lineage)
+	}
+	if !*plan {
+		return nil
+	}
+	rm, excluded, err := pf.apply(resources.FromState(merged, ""))
+	if err != nil {
+		return err
+	}
+	for _, e := range excluded {
+		log.Printf("%s %s, merge the states instead of importing it to keep its values", e.Address, e.Reason)
+	}
+	for _, d := range resources.Deposed(merged, "") {
+		log.Printf("deposed object %s won't be imported, destroy it outside of Terraform", d)
+	}
+	ordered, err := rm.Order()
+	if err != nil {
+		return err
+	}
+	return output(os.Stdout, ordered, generateOptions{
+		format:       *format,
+		rewrite:      cf.config.RewriteAddress,
+		excluded:     excluded,
+		instructions: pf.instructions(),
+	})
+}
@@ -0,0 +1,245 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/cmdpdx/tf-state-import/pkg/files"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+)
+
+// Stack is a state file processed independently of the others.
+type Stack struct {
+	// Name identifies the stack in the summary and names its output file. It is
+	// the path of the state file, relative to the directory it was found in,
+	// without the .tfstate extension.
+	Name      string
+	StateFile string
+}
+
+// Discover returns the state files matched by each pattern, ordered by name. A
+// pattern is either a directory, which is searched recursively for *.tfstate
+// files, or a glob of state files.
+func Discover(patterns ...string) ([]Stack, error) {
+	var stacks []Stack
+	for _, p := range patterns {
+		fi, err := os.Stat(p)
+		switch {
+		case err == nil && fi.IsDir():
+			found, err := walk(p)
+			if err != nil {
+				return nil, err
+			}
+			stacks = append(stacks, found...)
+		case err == nil:
+			stacks = append(stacks, Stack{Name: stackName(p), StateFile: p})
+		default:
+			matches, err := filepath.Glob(p)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", p, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("%s: no state files found", p)
+			}
+			for _, m := range matches {
+				stacks = append(stacks, Stack{Name: stackName(m), StateFile: m})
+			}
+		}
+	}
+
+	sort.Slice(stacks, func(i, j int) bool { return stacks[i].Name < stacks[j].Name })
+	deduped := stacks[:0]
+	for i, s := range stacks {
+		if i > 0 && s.Name == stacks[i-1].Name {
+			if filepath.Clean(s.StateFile) == filepath.Clean(stacks[i-1].StateFile) {
+				continue
+			}
+			return nil, fmt.Errorf("%s and %s are both named %s", stacks[i-1].StateFile, s.StateFile, s.Name)
+		}
+		deduped = append(deduped, s)
+	}
+	return deduped, nil
+}
+
+func walk(root string) ([]Stack, error) {
+	var stacks []Stack
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// .terraform holds the backend configuration, not state.
+		if d.IsDir() && d.Name() == ".terraform" {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".tfstate") {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		stacks = append(stacks, Stack{Name: stackName(rel), StateFile: path})
+		return nil
+	})
+	return stacks, err
+}
+
+func stackName(path string) string {
+	name := filepath.ToSlash(filepath.Clean(strings.TrimSuffix(path, ".tfstate")))
+	for strings.HasPrefix(name, "../") {
+		name = strings.TrimPrefix(name, "../")
+	}
+	return strings.TrimLeft(name, "/")
+}
+
+// Generator writes the output for a stack's resources and returns the number
+// of resources in it, and the resources of rm it left out and why.
+type Generator func(rm resources.ResourceMap, w io.Writer) (int, []resources.Skipped, error)
+
+// Options configure Run.
+type Options struct {
+	// OutDir is where each stack's output is written, to OutDir/Name+Extension.
+	OutDir    string
+	Extension string
+	// Provider filters the resources of every stack, see resources.Collect.
+	Provider    string
+	Parallelism int
+	Generate    Generator
+	// Force overwrites output files that already exist.
+	Force bool
+}
+
+// Result is the outcome of processing a single stack.
+type Result struct {
+	Stack  Stack
+	Output string
+	// Resources is the number of resources in the output.
+	Resources int
+	// Skipped are the resources that can't be imported, and those that the
+	// Generator left out.
+	Skipped []resources.Skipped
+	Err     error
+}
+
+// Run processes the stacks concurrently. A stack that fails doesn't stop the
+// others, its error is recorded in its result. Results are in the order of
+// stacks.
+func Run(ctx context.Context, stacks []Stack, opts Options) Summary {
+	parallelism := opts.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	results := make([]Result, len(stacks))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, s := range stacks {
+		wg.Add(1)
+		go func(i int, s Stack) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = process(ctx, s, opts)
+		}(i, s)
+	}
+	wg.Wait()
+	return Summary{Results: results}
+}
+
+func process(ctx context.Context, s Stack, opts Options) Result {
+	r := Result{Stack: s}
+	if r.Err = ctx.Err(); r.Err != nil {
+		return r
+	}
+	_, st, err := state.Read(ctx, s.StateFile)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	rm, skipped := resources.Collect(st, opts.Provider)
+	r.Skipped = skipped
+
+	// Generate fully before writing, so a failed stack leaves no partial output.
+	var buf bytes.Buffer
+	n, left, err := opts.Generate(rm, &buf)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	r.Skipped = append(r.Skipped, left...)
+	out := filepath.Join(opts.OutDir, filepath.FromSlash(s.Name)+opts.Extension)
+	if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil {
+		r.Err = err
+		return r
+	}
+	if r.Err = files.Write(out, buf.Bytes(), 0o644, opts.Force); r.Err != nil {
+		return r
+	}
+	r.Output, r.Resources = out, n
+	return r
+}
+
+// Summary is the aggregate of every stack's result.
+type Summary struct {
+	Results []Result
+}
+
+// Failed returns the number of stacks that failed.
+func (s Summary) Failed() int {
+	n := 0
+	for _, r := range s.Results {
+		if r.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Write writes a table of the stacks, the skipped resources and errors, and
+// the totals.
+func (s Summary) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STACK\tRESOURCES\tSKIPPED\tOUTPUT")
+	resourceCount, skippedCount := 0, 0
+	for _, r := range s.Results {
+		output := r.Output
+		if r.Err != nil {
+			output = "failed"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", r.Stack.Name, r.Resources, len(r.Skipped), output)
+		resourceCount += r.Resources
+		skippedCount += len(r.Skipped)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	var err error
+	printf := func(format string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+	for _, r := range s.Results {
+		for _, sk := range r.Skipped {
+			printf("skipped %s: %s: %s\n", r.Stack.Name, sk.Address, sk.Reason)
+		}
+	}
+	for _, r := range s.Results {
+		if r.Err != nil {
+			printf("error   %s: %v\n", r.Stack.Name, r.Err)
+		}
+	}
+	printf("%d stacks: %d resources, %d skipped, %d failed\n", len(s.Results), resourceCount, skippedCount, s.Failed())
+	return err
+}
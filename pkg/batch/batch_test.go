@@ -0,0 +1,164 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+)
+
+const stateWithSkipped = `{
+  "version": 4,
+  "resources": [{
+    "mode": "managed",
+    "type": "t",
+    "name": "ok",
+    "provider": "provider[\"registry.terraform.io/example/t\"]",
+    "instances": [{"attributes": {"id": "a"}}]
+  }, {
+    "mode": "managed",
+    "type": "t",
+    "name": "left_out",
+    "provider": "provider[\"registry.terraform.io/example/t\"]",
+    "instances": [{"attributes": {"id": "b"}}]
+  }, {
+    "mode": "managed",
+    "type": "t",
+    "name": "no_id",
+    "provider": "provider[\"registry.terraform.io/example/t\"]",
+    "instances": [{"attributes": {}}]
+  }]
+}`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	root := t.TempDir()
+	for _, f := range []string{
+		"prod/network/terraform.tfstate",
+		"prod/network/terraform.tfstate.backup",
+		"prod/network/.terraform/terraform.tfstate",
+		"staging/app.tfstate",
+		"README.md",
+	} {
+		writeFile(t, filepath.Join(root, f), "{}")
+	}
+
+	got, err := Discover(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Stack{
+		{Name: "prod/network/terraform", StateFile: filepath.Join(root, "prod/network/terraform.tfstate")},
+		{Name: "staging/app", StateFile: filepath.Join(root, "staging/app.tfstate")},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("Discover(dir) return mismatch (-want, +got):", diff)
+	}
+
+	// Globs and files are named by their path.
+	app := filepath.Join(root, "staging/app.tfstate")
+	got, err = Discover(filepath.Join(root, "*/*.tfstate"), app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []Stack{{Name: strings.TrimPrefix(filepath.ToSlash(root), "/") + "/staging/app", StateFile: app}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("Discover(glob) return mismatch (-want, +got):", diff)
+	}
+
+	if _, err := Discover("missing/*.tfstate"); err == nil {
+		t.Error("Discover() of a glob without matches succeeded")
+	}
+}
+
+func TestRun(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "stacks/a.tfstate"), stateWithSkipped)
+	writeFile(t, filepath.Join(root, "stacks/b.tfstate"), "not json")
+	writeFile(t, filepath.Join(root, "stacks/nested/c.tfstate"), stateWithSkipped)
+	stacks, err := Discover(filepath.Join(root, "stacks"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outDir := filepath.Join(root, "out")
+	opts := Options{
+		OutDir:      outDir,
+		Extension:   ".sh",
+		Parallelism: 2,
+		// Generate leaves t.left_out out of its output, like the resources
+		// that policies leave in state.
+		Generate: func(rm resources.ResourceMap, w io.Writer) (int, []resources.Skipped, error) {
+			if _, ok := rm["t.ok"]; !ok {
+				return 0, nil, errors.New("t.ok is missing")
+			}
+			_, err := fmt.Fprintf(w, "%d resources\n", len(rm)-1)
+			return len(rm) - 1, []resources.Skipped{{Address: "t.left_out", Reason: "it's left out"}}, err
+		},
+	}
+	summary := Run(context.Background(), stacks, opts)
+
+	if got := summary.Failed(); got != 1 {
+		t.Errorf("Failed() = %d, want 1", got)
+	}
+	for _, name := range []string{"a.sh", "nested/c.sh"} {
+		bs, err := os.ReadFile(filepath.Join(outDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(bs) != "1 resources\n" {
+			t.Errorf("output %s = %q", name, bs)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "b.sh")); err == nil {
+		t.Error("failed stack b wrote output")
+	}
+
+	var b strings.Builder
+	if err := summary.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.ReplaceAll(b.String(), root, "ROOT")
+	// The parse error is the only part of the output that isn't ours.
+	got = got[:strings.Index(got, "error   b: ")+len("error   b: ")] + "..." + got[strings.LastIndex(got, "\n3 stacks"):]
+	want := `STACK     RESOURCES  SKIPPED  OUTPUT
+a         1          2        ROOT/out/a.sh
+b         0          0        failed
+nested/c  1          2        ROOT/out/nested/c.sh
+skipped a: t.no_id: resource doesn't have an id attribute, and no import rule for its type builds one from other attributes
+skipped a: t.left_out: it's left out
+skipped nested/c: t.no_id: resource doesn't have an id attribute, and no import rule for its type builds one from other attributes
+skipped nested/c: t.left_out: it's left out
+error   b: ...
+3 stacks: 2 resources, 4 skipped, 1 failed
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("Write() output mismatch (-want, +got):", diff)
+	}
+
+	// Outputs are only overwritten with Force.
+	if r := Run(context.Background(), stacks[:1], opts).Results[0]; r.Err == nil || !strings.Contains(r.Err.Error(), "refusing to overwrite") {
+		t.Errorf("Run() of an existing output = %v, want an error refusing to overwrite it", r.Err)
+	}
+	opts.Force = true
+	if r := Run(context.Background(), stacks[:1], opts).Results[0]; r.Err != nil {
+		t.Errorf("Run() with Force = %v, want no error", r.Err)
+	}
+}
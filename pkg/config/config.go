@@ -0,0 +1,292 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/maps"
+
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+)
+
+// Filenames are the config files discovered in the working directory, in order
+// of preference.
+var Filenames = []string{".tf-state-import.yaml", ".tf-state-import.yml"}
+
+type kind int
+
+const (
+	stringKind kind = iota
+	boolKind
+	intKind
+	durationKind
+)
+
+// setting is a config key that sets the default of a command line flag.
+type setting struct {
+	key  string
+	flag string
+	kind kind
+}
+
+var settings = []setting{
+	{"state", "tfstate", stringKind},
+	{"provider", "provider", stringKind},
+	{"format", "format", stringKind},
+	{"tainted", "tainted", stringKind},
+	{"non_importable", "non-importable", stringKind},
+	{"include_remove", "include-remove", boolKind},
+	{"rollback_dir", "rollback-dir", stringKind},
+	{"execution.binary", "binary", stringKind},
+	{"execution.parallelism", "parallelism", intKind},
+	{"execution.lock_timeout", "lock-timeout", durationKind},
+	{"execution.lock_retries", "lock-retries", intKind},
+	{"execution.serialize_writes", "serialize-writes", boolKind},
+	{"execution.journal", "journal", stringKind},
+}
+
+// Config is a project configuration file.
+type Config struct {
+	// Flags are flag values by flag name. Flags given on the command line
+	// take precedence.
+	Flags map[string]string
+	// Rules are import ID templates by resource type, see resources.TemplateRule.
+	Rules map[string]string
+	// Rewrites map address prefixes in state to the address to import to,
+	// for resources that moved in the configuration.
+	Rewrites map[string]string
+	// Sensitive are patterns of attribute names whose values are redacted,
+	// in addition to redact.DefaultPatterns.
+	Sensitive []string
+}
+
+// Discover returns the config file in dir, if there is one.
+func Discover(dir string) (string, bool) {
+	for _, name := range Filenames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// Load reads and validates the config file.
+func Load(filename string) (Config, error) {
+	bs, err := os.ReadFile(filename)
+	if err != nil {
+		return Config{}, err
+	}
+	c, err := Parse(string(bs))
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", filename, err)
+	}
+	return c, nil
+}
+
+// Parse parses and validates the contents of a config file.
+func Parse(data string) (Config, error) {
+	doc, err := parseYAML(data)
+	if err != nil {
+		return Config{}, err
+	}
+
+	c := Config{
+		Flags:    map[string]string{},
+		Rules:    map[string]string{},
+		Rewrites: map[string]string{},
+	}
+	var errs []error
+	known := map[string]bool{"rules": true, "rewrites": true, "execution": true, "sensitive_attributes": true}
+	for _, s := range settings {
+		known[s.key] = true
+		v, ok, err := lookup(doc, s.key)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := s.validate(v); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		c.Flags[s.flag] = v
+	}
+	if f, ok := c.Flags["format"]; ok && f != "command" && f != "block" {
+		errs = append(errs, fmt.Errorf("format: must be 'command' or 'block', got %q", f))
+	}
+	if f, ok := c.Flags["tainted"]; ok && f != "taint" && f != "skip" && f != "fail" {
+		errs = append(errs, fmt.Errorf("tainted: must be 'taint', 'skip' or 'fail', got %q", f))
+	}
+	if f, ok := c.Flags["non-importable"]; ok && f != "keep" && f != "instructions" && f != "import" {
+		errs = append(errs, fmt.Errorf("non_importable: must be 'keep', 'instructions' or 'import', got %q", f))
+	}
+
+	errs = append(errs, unknownKeys(doc, "", known)...)
+
+	if c.Rules, err = stringMap(doc, "rules"); err != nil {
+		errs = append(errs, err)
+	}
+	for typ, template := range c.Rules {
+		if _, err := resources.TemplateRule(typ, template); err != nil {
+			errs = append(errs, fmt.Errorf("rules: %w", err))
+		}
+	}
+
+	if c.Rewrites, err = stringMap(doc, "rewrites"); err != nil {
+		errs = append(errs, err)
+	}
+	for from, to := range c.Rewrites {
+		if from == "" || to == "" {
+			errs = append(errs, fmt.Errorf("rewrites: %q -> %q: addresses must not be empty", from, to))
+		}
+	}
+
+	if c.Sensitive, err = stringList(doc, "sensitive_attributes"); err != nil {
+		errs = append(errs, err)
+	}
+	for _, pattern := range c.Sensitive {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("sensitive_attributes: %q: %w", pattern, err))
+		}
+	}
+
+	return c, errors.Join(errs...)
+}
+
+func (s setting) validate(v string) error {
+	var err error
+	switch s.kind {
+	case boolKind:
+		_, err = strconv.ParseBool(v)
+	case intKind:
+		_, err = strconv.Atoi(v)
+	case durationKind:
+		_, err = time.ParseDuration(v)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: invalid value %q", s.key, v)
+	}
+	return nil
+}
+
+// lookup returns the scalar at the dotted key.
+func lookup(doc map[string]interface{}, key string) (string, bool, error) {
+	parts := strings.Split(key, ".")
+	var cur interface{} = doc
+	for i, p := range parts {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return "", false, fmt.Errorf("%s: expected a map", strings.Join(parts[:i], "."))
+		}
+		if cur, ok = m[p]; !ok {
+			return "", false, nil
+		}
+	}
+	s, ok := cur.(string)
+	if !ok {
+		return "", false, fmt.Errorf("%s: expected a single value", key)
+	}
+	return s, true, nil
+}
+
+func stringMap(doc map[string]interface{}, key string) (map[string]string, error) {
+	out := map[string]string{}
+	v, ok := doc[key]
+	if !ok {
+		return out, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return out, fmt.Errorf("%s: expected a map", key)
+	}
+	for k, v := range m {
+		s, ok := v.(string)
+		if !ok {
+			return out, fmt.Errorf("%s.%s: expected a single value", key, k)
+		}
+		out[k] = s
+	}
+	return out, nil
+}
+
+// stringList returns the list at key. A single value is a list of one.
+func stringList(doc map[string]interface{}, key string) ([]string, error) {
+	switch v := doc[key].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		out := make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s[%d]: expected a single value", key, i)
+			}
+			out[i] = s
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("%s: expected a list", key)
+}
+
+func unknownKeys(doc map[string]interface{}, prefix string, known map[string]bool) []error {
+	var errs []error
+	keys := maps.Keys(doc)
+	sort.Strings(keys)
+	for _, k := range keys {
+		key := prefix + k
+		if !known[key] {
+			errs = append(errs, fmt.Errorf("%s: unknown setting", key))
+			continue
+		}
+		// Only execution has nested settings, rules and rewrites are free-form.
+		if sub, ok := doc[k].(map[string]interface{}); ok && key == "execution" {
+			errs = append(errs, unknownKeys(sub, key+".", known)...)
+		}
+	}
+	return errs
+}
+
+// ImportRules returns the rules as resource rules, ordered by type.
+func (c Config) ImportRules() ([]resources.Rule, error) {
+	types := maps.Keys(c.Rules)
+	sort.Strings(types)
+	rs := make([]resources.Rule, 0, len(types))
+	for _, typ := range types {
+		r, err := resources.TemplateRule(typ, c.Rules[typ])
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
+
+// RewriteAddress returns the address to import the resource at address to. The
+// longest rewrite whose prefix matches whole address segments is applied.
+func (c Config) RewriteAddress(address string) string {
+	best := ""
+	for from := range c.Rewrites {
+		if len(from) <= len(best) || !strings.HasPrefix(address, from) {
+			continue
+		}
+		if rest := address[len(from):]; rest == "" || rest[0] == '.' || rest[0] == '[' {
+			best = from
+		}
+	}
+	if best == "" {
+		return address
+	}
+	return c.Rewrites[best] + address[len(best):]
+}
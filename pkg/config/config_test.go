@@ -0,0 +1,144 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const example = `# Migrate the google resources of this stack.
+state: ./terraform.tfstate
+provider: hashicorp/google
+format: block
+include_remove: false
+tainted: skip
+non_importable: instructions
+
+rules:
+  google_foo_bar: "{project}/{name}"
+
+rewrites:
+  module.old: module.new
+
+sensitive_attributes:
+  - connection_string
+  - "*_pem"
+
+execution:
+  binary: tofu
+  parallelism: 4
+  lock_timeout: 30s
+`
+
+func TestParse(t *testing.T) {
+	got, err := Parse(example)
+	if err != nil {
+		t.Fatalf("Parse() = %v", err)
+	}
+	want := Config{
+		Flags: map[string]string{
+			"tfstate":        "./terraform.tfstate",
+			"provider":       "hashicorp/google",
+			"format":         "block",
+			"include-remove": "false",
+			"tainted":        "skip",
+			"non-importable": "instructions",
+			"binary":         "tofu",
+			"parallelism":    "4",
+			"lock-timeout":   "30s",
+		},
+		Rules:     map[string]string{"google_foo_bar": "{project}/{name}"},
+		Rewrites:  map[string]string{"module.old": "module.new"},
+		Sensitive: []string{"connection_string", "*_pem"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("Parse() return mismatch (-want, +got):", diff)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		data    string
+		wantErr []string
+	}{{
+		name:    "unknown settings",
+		data:    "stat: x\nexecution:\n  bin: y\n",
+		wantErr: []string{"stat: unknown setting", "execution.bin: unknown setting"},
+	}, {
+		name:    "bad values",
+		data:    "include_remove: maybe\nformat: xml\ntainted: ignore\nnon_importable: drop\nexecution:\n  parallelism: many\n  lock_timeout: soon\n",
+		wantErr: []string{"include_remove: invalid value", "format: must be", "tainted: must be", "non_importable: must be", "execution.parallelism: invalid value", "execution.lock_timeout: invalid value"},
+	}, {
+		name:    "bad rule",
+		data:    "rules:\n  t: \"{a\"\n",
+		wantErr: []string{"rules: rule for t: unbalanced braces"},
+	}, {
+		name:    "wrong shapes",
+		data:    "state: [a, b]\nrules: x\nexecution: y\n",
+		wantErr: []string{"state: expected a single value", "rules: expected a map", "execution: expected a map"},
+	}, {
+		name:    "bad sensitive pattern",
+		data:    "sensitive_attributes: [\"[a\"]\n",
+		wantErr: []string{`sensitive_attributes: "[a": syntax error in pattern`},
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.data)
+			if err == nil {
+				t.Fatal("Parse() succeeded, want error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Parse() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDiscoverAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	if _, ok := Discover(dir); ok {
+		t.Fatal("Discover() found a config in an empty directory")
+	}
+
+	path := filepath.Join(dir, ".tf-state-import.yml")
+	if err := os.WriteFile(path, []byte(example), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := Discover(dir)
+	if !ok || got != path {
+		t.Fatalf("Discover() = %q, %t, want %q", got, ok, path)
+	}
+	if _, err := Load(got); err != nil {
+		t.Errorf("Load() = %v", err)
+	}
+}
+
+func TestRewriteAddress(t *testing.T) {
+	c := Config{Rewrites: map[string]string{
+		"module.old":         "module.new",
+		"module.old.t.name":  "t.moved",
+		"t.renamed":          "t.name",
+		"module.collection":  "module.c",
+		"module.partial_mat": "module.nope",
+	}}
+
+	for address, want := range map[string]string{
+		"module.old.t.other":       "module.new.t.other",
+		"module.old.t.name":        "t.moved",
+		"module.old.t.name[\"k\"]": "t.moved[\"k\"]",
+		"t.renamed":                "t.name",
+		"module.collection[0].t.x": "module.c[0].t.x",
+		"module.partial_match.t.x": "module.partial_match.t.x",
+		"module.older.t.x":         "module.older.t.x",
+		"unrelated.address":        "unrelated.address",
+	} {
+		if got := c.RewriteAddress(address); got != want {
+			t.Errorf("RewriteAddress(%q) = %q, want %q", address, got, want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The config file is YAML. Scalars are always decoded as strings, Parse
+// validates them against the type of the setting.
+
+// parseYAML parses the document into nested map[string]interface{},
+// []interface{} and string values.
+func parseYAML(data string) (map[string]interface{}, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(data), &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	root := resolveAlias(doc.Content[0])
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: expected a map at the top level", root.Line)
+	}
+	v, err := yamlValue(root)
+	if err != nil {
+		return nil, err
+	}
+	m, _ := v.(map[string]interface{})
+	return m, nil
+}
+
+func yamlValue(n *yaml.Node) (interface{}, error) {
+	n = resolveAlias(n)
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if n.Tag == "!!null" {
+			return "", nil
+		}
+		return n.Value, nil
+	case yaml.SequenceNode:
+		items := []interface{}{}
+		for _, item := range n.Content {
+			item = resolveAlias(item)
+			if item.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: lists of maps or lists are not supported", item.Line)
+			}
+			v, err := yamlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+	case yaml.MappingNode:
+		m := map[string]interface{}{}
+		if err := mergeMapping(m, n, map[string]bool{}); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("line %d: unsupported YAML node", n.Line)
+}
+
+// mergeMapping adds the keys of the mapping n to m. Keys merged in with `<<`
+// are overridden by the keys of n, and may not be defined twice by n itself.
+func mergeMapping(m map[string]interface{}, n *yaml.Node, own map[string]bool) error {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := resolveAlias(n.Content[i]), n.Content[i+1]
+		if k.Kind != yaml.ScalarNode {
+			return fmt.Errorf("line %d: keys must be strings", k.Line)
+		}
+		if k.Tag == "!!merge" {
+			if err := merge(m, resolveAlias(v)); err != nil {
+				return err
+			}
+			continue
+		}
+		if own[k.Value] {
+			return fmt.Errorf("line %d: duplicate key %q", k.Line, k.Value)
+		}
+		own[k.Value] = true
+		value, err := yamlValue(v)
+		if err != nil {
+			return err
+		}
+		m[k.Value] = value
+	}
+	return nil
+}
+
+// merge adds the keys of the merge key value v, a mapping or a list of them,
+// that m doesn't have yet.
+func merge(m map[string]interface{}, v *yaml.Node) error {
+	sources := []*yaml.Node{v}
+	if v.Kind == yaml.SequenceNode {
+		sources = v.Content
+	}
+	for _, s := range sources {
+		s = resolveAlias(s)
+		if s.Kind != yaml.MappingNode {
+			return fmt.Errorf("line %d: '<<' expects a map", s.Line)
+		}
+		merged := map[string]interface{}{}
+		if err := mergeMapping(merged, s, map[string]bool{}); err != nil {
+			return err
+		}
+		for k, mv := range merged {
+			if _, ok := m[k]; !ok {
+				m[k] = mv
+			}
+		}
+	}
+	return nil
+}
+
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseYAML(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		data    string
+		want    map[string]interface{}
+		wantErr bool
+	}{{
+		name: "empty",
+		data: "# nothing here\n\n",
+		want: map[string]interface{}{},
+	}, {
+		name: "scalars and comments",
+		data: `---
+plain: value # trailing comment
+double: "with # hash"
+single: 'it''s'
+url: http://example.com/#anchor
+empty:
+`,
+		want: map[string]interface{}{
+			"plain":  "value",
+			"double": "with # hash",
+			"single": "it's",
+			"url":    "http://example.com/#anchor",
+			"empty":  "",
+		},
+	}, {
+		name: "nested maps",
+		data: `outer:
+  inner:
+    key: v
+  other: w
+top: x
+`,
+		want: map[string]interface{}{
+			"outer": map[string]interface{}{
+				"inner": map[string]interface{}{"key": "v"},
+				"other": "w",
+			},
+			"top": "x",
+		},
+	}, {
+		name: "lists",
+		data: `block:
+  - a
+  - "b c"
+same_indent:
+- d
+inline: [e, 'f', "g"]
+none: []
+`,
+		want: map[string]interface{}{
+			"block":       []interface{}{"a", "b c"},
+			"same_indent": []interface{}{"d"},
+			"inline":      []interface{}{"e", "f", "g"},
+			"none":        []interface{}{},
+		},
+	}, {
+		name: "quoted keys",
+		data: `"module.old[\"a\"]": module.new
+`,
+		want: map[string]interface{}{
+			`module.old["a"]`: "module.new",
+		},
+	}, {
+		name:    "bad indentation",
+		data:    "a:\n    b: c\n  d: e\n",
+		wantErr: true,
+	}, {
+		name:    "duplicate key",
+		data:    "a: b\na: c\n",
+		wantErr: true,
+	}, {
+		name: "flow collections",
+		data: `a: {b: c, "d, e": [f, "g, h"]}
+`,
+		want: map[string]interface{}{
+			"a": map[string]interface{}{"b": "c", "d, e": []interface{}{"f", "g, h"}},
+		},
+	}, {
+		name: "anchors and merge keys",
+		data: `base: &base
+  region: eu
+  tags: &tags [a, b]
+prod:
+  <<: *base
+  region: us
+copy: *tags
+`,
+		want: map[string]interface{}{
+			"base": map[string]interface{}{"region": "eu", "tags": []interface{}{"a", "b"}},
+			"prod": map[string]interface{}{"region": "us", "tags": []interface{}{"a", "b"}},
+			"copy": []interface{}{"a", "b"},
+		},
+	}, {
+		name: "multi-line scalars",
+		data: `literal: |
+  one
+  two
+folded: >-
+  one
+  two
+`,
+		want: map[string]interface{}{
+			"literal": "one\ntwo\n",
+			"folded":  "one two",
+		},
+	}, {
+		name: "typed scalars",
+		data: "n: 4\nb: true\nnull: ~\n",
+		want: map[string]interface{}{"n": "4", "b": "true", "null": ""},
+	}, {
+		name:    "list of maps",
+		data:    "a:\n  - b: c\n",
+		wantErr: true,
+	}, {
+		name:    "not key value",
+		data:    "just text\n",
+		wantErr: true,
+	}, {
+		name:    "tab indentation",
+		data:    "a:\n\tb: c\n",
+		wantErr: true,
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAML(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseYAML() error = %v, want error: %t", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error("parseYAML() return mismatch (-want, +got):", diff)
+			}
+		})
+	}
+}
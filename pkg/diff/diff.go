@@ -0,0 +1,206 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	"golang.org/x/exp/maps"
+
+	"github.com/cmdpdx/tf-state-import/pkg/redact"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+)
+
+// Kind is how a resource or attribute differs between two states.
+type Kind string
+
+const (
+	Added   Kind = "added"
+	Removed Kind = "removed"
+	Changed Kind = "changed"
+)
+
+// AttributeChange is a single changed value. Path addresses nested values
+// the way Terraform does, e.g. `labels.env` or `rule[0].name`.
+type AttributeChange struct {
+	Path string      `json:"path"`
+	Kind Kind        `json:"kind"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// Resource is a resource that differs between two states.
+type Resource struct {
+	Address    string            `json:"address"`
+	Kind       Kind              `json:"kind"`
+	OldID      string            `json:"old_id,omitempty"`
+	NewID      string            `json:"new_id,omitempty"`
+	Attributes []AttributeChange `json:"attributes,omitempty"`
+}
+
+// Result is every resource that differs, ordered by address.
+type Result struct {
+	Resources []Resource `json:"resources"`
+}
+
+// Empty reports whether the states are equivalent.
+func (r Result) Empty() bool {
+	return len(r.Resources) == 0
+}
+
+// States aligns resources by address and returns those that were added,
+// removed, or whose ID or attributes changed. Values are compared before the
+// policy redacts them, so changes of sensitive values are still reported.
+func States(before, after resources.ResourceMap, policy redact.Policy) Result {
+	addresses := maps.Keys(before)
+	for a := range after {
+		if _, ok := before[a]; !ok {
+			addresses = append(addresses, a)
+		}
+	}
+	sort.Strings(addresses)
+
+	r := Result{Resources: []Resource{}}
+	for _, a := range addresses {
+		o, inBefore := before[a]
+		n, inAfter := after[a]
+		switch {
+		case !inAfter:
+			r.Resources = append(r.Resources, Resource{Address: a, Kind: Removed, OldID: o.ID})
+		case !inBefore:
+			r.Resources = append(r.Resources, Resource{Address: a, Kind: Added, NewID: n.ID})
+		default:
+			changes := Attributes(o.Attributes, n.Attributes)
+			if o.ID == n.ID && len(changes) == 0 {
+				continue
+			}
+			for i, c := range changes {
+				changes[i].Old = policy.Value(c.Path, c.Old, o.Sensitive)
+				changes[i].New = policy.Value(c.Path, c.New, n.Sensitive)
+			}
+			r.Resources = append(r.Resources, Resource{
+				Address:    a,
+				Kind:       Changed,
+				OldID:      o.ID,
+				NewID:      n.ID,
+				Attributes: changes,
+			})
+		}
+	}
+	return r
+}
+
+// Attributes returns the changes between two sets of attributes, descending into
+// maps and lists so that only the values that actually changed are reported.
+func Attributes(before, after map[string]interface{}) []AttributeChange {
+	var changes []AttributeChange
+	compareMaps("", before, after, &changes)
+	return changes
+}
+
+func compare(path string, before, after interface{}, changes *[]AttributeChange) {
+	switch o := before.(type) {
+	case map[string]interface{}:
+		if n, ok := after.(map[string]interface{}); ok {
+			compareMaps(path, o, n, changes)
+			return
+		}
+	case []interface{}:
+		if n, ok := after.([]interface{}); ok {
+			compareLists(path, o, n, changes)
+			return
+		}
+	}
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, AttributeChange{Path: path, Kind: Changed, Old: before, New: after})
+	}
+}
+
+func compareMaps(path string, before, after map[string]interface{}, changes *[]AttributeChange) {
+	keys := maps.Keys(before)
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		p := redact.JoinKey(path, k)
+		o, inBefore := before[k]
+		n, inAfter := after[k]
+		switch {
+		case !inAfter:
+			*changes = append(*changes, AttributeChange{Path: p, Kind: Removed, Old: o})
+		case !inBefore:
+			*changes = append(*changes, AttributeChange{Path: p, Kind: Added, New: n})
+		default:
+			compare(p, o, n, changes)
+		}
+	}
+}
+
+func compareLists(path string, before, after []interface{}, changes *[]AttributeChange) {
+	for i := 0; i < len(before) || i < len(after); i++ {
+		p := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= len(after):
+			*changes = append(*changes, AttributeChange{Path: p, Kind: Removed, Old: before[i]})
+		case i >= len(before):
+			*changes = append(*changes, AttributeChange{Path: p, Kind: Added, New: after[i]})
+		default:
+			compare(p, before[i], after[i], changes)
+		}
+	}
+}
+
+// WriteText writes the result in a form similar to `terraform plan`.
+func (r Result) WriteText(w io.Writer) error {
+	var err error
+	printf := func(format string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+
+	symbols := map[Kind]string{Added: "+", Removed: "-", Changed: "~"}
+	for _, res := range r.Resources {
+		printf("%s %s\n", symbols[res.Kind], res.Address)
+		// The ID is usually also the `id` attribute, don't report it twice.
+		if res.Kind == Changed && res.OldID != res.NewID && !hasPath(res.Attributes, "id") {
+			printf("    ~ id: %q -> %q\n", res.OldID, res.NewID)
+		}
+		for _, c := range res.Attributes {
+			switch c.Kind {
+			case Added:
+				printf("    + %s: %s\n", c.Path, redact.Format(c.New))
+			case Removed:
+				printf("    - %s: %s\n", c.Path, redact.Format(c.Old))
+			default:
+				printf("    ~ %s: %s -> %s\n", c.Path, redact.Format(c.Old), redact.Format(c.New))
+			}
+		}
+	}
+	if r.Empty() {
+		printf("no differences\n")
+	}
+	return err
+}
+
+func hasPath(changes []AttributeChange, path string) bool {
+	for _, c := range changes {
+		if c.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteJSON writes the result as indented JSON.
+func (r Result) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
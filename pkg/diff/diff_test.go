@@ -0,0 +1,215 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cmdpdx/tf-state-import/pkg/redact"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+)
+
+func TestAttributes(t *testing.T) {
+	for _, tt := range []struct {
+		name          string
+		before, after map[string]interface{}
+		want          []AttributeChange
+	}{{
+		name:   "equal",
+		before: map[string]interface{}{"a": "x", "b": []interface{}{float64(1)}},
+		after:  map[string]interface{}{"a": "x", "b": []interface{}{float64(1)}},
+	}, {
+		name:   "top level",
+		before: map[string]interface{}{"changed": "x", "removed": true},
+		after:  map[string]interface{}{"changed": "y", "added": float64(1)},
+		want: []AttributeChange{
+			{Path: "added", Kind: Added, New: float64(1)},
+			{Path: "changed", Kind: Changed, Old: "x", New: "y"},
+			{Path: "removed", Kind: Removed, Old: true},
+		},
+	}, {
+		name: "nested maps",
+		before: map[string]interface{}{
+			"labels": map[string]interface{}{"env": "prod", "team": "a", "k8s.io/name": "x"},
+		},
+		after: map[string]interface{}{
+			"labels": map[string]interface{}{"env": "dev", "team": "a", "k8s.io/name": "y"},
+		},
+		want: []AttributeChange{
+			{Path: "labels.env", Kind: Changed, Old: "prod", New: "dev"},
+			{Path: `labels["k8s.io/name"]`, Kind: Changed, Old: "x", New: "y"},
+		},
+	}, {
+		name: "lists of objects",
+		before: map[string]interface{}{
+			"rule": []interface{}{
+				map[string]interface{}{"name": "a", "priority": float64(1)},
+				map[string]interface{}{"name": "b"},
+			},
+		},
+		after: map[string]interface{}{
+			"rule": []interface{}{
+				map[string]interface{}{"name": "a", "priority": float64(2)},
+			},
+		},
+		want: []AttributeChange{
+			{Path: "rule[0].priority", Kind: Changed, Old: float64(1), New: float64(2)},
+			{Path: "rule[1]", Kind: Removed, Old: map[string]interface{}{"name": "b"}},
+		},
+	}, {
+		name:   "type change",
+		before: map[string]interface{}{"a": []interface{}{"x"}},
+		after:  map[string]interface{}{"a": "x"},
+		want: []AttributeChange{
+			{Path: "a", Kind: Changed, Old: []interface{}{"x"}, New: "x"},
+		},
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Attributes(tt.before, tt.after)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error("Attributes() return mismatch (-want, +got):", diff)
+			}
+		})
+	}
+}
+
+var (
+	oldState = resources.ResourceMap{
+		"t.same":    {Type: "t", Name: "same", ID: "same", Attributes: map[string]interface{}{"id": "same"}},
+		"t.removed": {Type: "t", Name: "removed", ID: "removed"},
+		"t.changed": {
+			Type:       "t",
+			Name:       "changed",
+			ID:         "old",
+			Attributes: map[string]interface{}{"id": "old", "tags": []interface{}{"a"}},
+		},
+	}
+	newState = resources.ResourceMap{
+		"t.same":  {Type: "t", Name: "same", ID: "same", Attributes: map[string]interface{}{"id": "same"}},
+		"t.added": {Type: "t", Name: "added", ID: "added"},
+		"t.changed": {
+			Type:       "t",
+			Name:       "changed",
+			ID:         "new",
+			Attributes: map[string]interface{}{"id": "new", "tags": []interface{}{"a", "b"}},
+		},
+	}
+)
+
+func TestStates(t *testing.T) {
+	want := Result{Resources: []Resource{{
+		Address: "t.added",
+		Kind:    Added,
+		NewID:   "added",
+	}, {
+		Address: "t.changed",
+		Kind:    Changed,
+		OldID:   "old",
+		NewID:   "new",
+		Attributes: []AttributeChange{
+			{Path: "id", Kind: Changed, Old: "old", New: "new"},
+			{Path: "tags[1]", Kind: Added, New: "b"},
+		},
+	}, {
+		Address: "t.removed",
+		Kind:    Removed,
+		OldID:   "removed",
+	}}}
+
+	got := States(oldState, newState, redact.Default)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("States() return mismatch (-want, +got):", diff)
+	}
+	if !States(oldState, oldState, redact.Default).Empty() {
+		t.Error("States() of identical states is not empty")
+	}
+}
+
+func TestStatesRedacted(t *testing.T) {
+	before := resources.ResourceMap{"t.db": {
+		ID:         "db",
+		Attributes: map[string]interface{}{"password": "a", "conn": map[string]interface{}{"host": "h1", "key": "k1"}},
+		Sensitive:  []string{"conn.key"},
+	}}
+	after := resources.ResourceMap{"t.db": {
+		ID:         "db",
+		Attributes: map[string]interface{}{"password": "b", "conn": map[string]interface{}{"host": "h2", "key": "k2"}},
+		Sensitive:  []string{"conn.key"},
+	}}
+
+	want := []AttributeChange{
+		{Path: "conn.host", Kind: Changed, Old: "h1", New: "h2"},
+		{Path: "conn.key", Kind: Changed, Old: redact.Marker, New: redact.Marker},
+		{Path: "password", Kind: Changed, Old: redact.Marker, New: redact.Marker},
+	}
+	got := States(before, after, redact.Default)
+	if diff := cmp.Diff(want, got.Resources[0].Attributes); diff != "" {
+		t.Error("States() attributes mismatch (-want, +got):", diff)
+	}
+
+	shown := States(before, after, redact.Policy{Disabled: true})
+	if v := shown.Resources[0].Attributes[2].New; v != "b" {
+		t.Errorf("States() with redaction disabled = %v, want b", v)
+	}
+}
+
+func TestResultWrite(t *testing.T) {
+	r := States(oldState, newState, redact.Default)
+
+	var text strings.Builder
+	if err := r.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	wantText := `+ t.added
+~ t.changed
+    ~ id: "old" -> "new"
+    + tags[1]: "b"
+- t.removed
+`
+	if diff := cmp.Diff(wantText, text.String()); diff != "" {
+		t.Error("WriteText() output mismatch (-want, +got):", diff)
+	}
+
+	var js strings.Builder
+	if err := r.WriteJSON(&js); err != nil {
+		t.Fatal(err)
+	}
+	wantJSON := `{
+  "resources": [
+    {
+      "address": "t.added",
+      "kind": "added",
+      "new_id": "added"
+    },
+    {
+      "address": "t.changed",
+      "kind": "changed",
+      "old_id": "old",
+      "new_id": "new",
+      "attributes": [
+        {
+          "path": "id",
+          "kind": "changed",
+          "old": "old",
+          "new": "new"
+        },
+        {
+          "path": "tags[1]",
+          "kind": "added",
+          "new": "b"
+        }
+      ]
+    },
+    {
+      "address": "t.removed",
+      "kind": "removed",
+      "old_id": "removed"
+    }
+  ]
+}
+`
+	if diff := cmp.Diff(wantJSON, js.String()); diff != "" {
+		t.Error("WriteJSON() output mismatch (-want, +got):", diff)
+	}
+}
@@ -0,0 +1,243 @@
+package execute
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+)
+
+// Action is a state operation performed on a single resource.
+type Action string
+
+const (
+	Remove Action = "rm"
+	Import Action = "import"
+	// Taint marks an imported resource that was tainted in the original state
+	// for replacement again.
+	Taint Action = "taint"
+)
+
+// Step is one `terraform state rm`, `terraform import` or `terraform taint`
+// invocation.
+type Step struct {
+	Action  Action
+	Address string
+	ID      string
+}
+
+// Args returns the terraform arguments that perform the step, with the given
+// flags placed after the subcommand.
+func (s Step) Args(flags ...string) []string {
+	switch s.Action {
+	case Remove:
+		return append(append([]string{"state", "rm"}, flags...), s.Address)
+	case Taint:
+		return append(append([]string{"taint"}, flags...), s.Address)
+	default:
+		return append(append([]string{"import"}, flags...), s.Address, s.ID)
+	}
+}
+
+func (s Step) String() string {
+	return fmt.Sprintf("%s %s", s.Action, s.Address)
+}
+
+// Plan returns the steps that remove the ordered resources from most to least
+// dependent, import them from least to most dependent, and then taint the
+// resources that were tainted.
+func Plan(ordered []*resources.Tuple, includeRemove bool) []Step {
+	steps := make([]Step, 0, 2*len(ordered))
+	if includeRemove {
+		for i := len(ordered) - 1; i >= 0; i-- {
+			steps = append(steps, Step{Action: Remove, Address: ordered[i].Address()})
+		}
+	}
+	for _, r := range ordered {
+		steps = append(steps, Step{Action: Import, Address: r.Address(), ID: r.ImportableID()})
+	}
+	for _, r := range ordered {
+		if r.Tainted {
+			steps = append(steps, Step{Action: Taint, Address: r.Address()})
+		}
+	}
+	return steps
+}
+
+// Runner runs a terraform command. It is an interface so tests can swap in a fake.
+type Runner interface {
+	Run(ctx context.Context, args []string, stdout, stderr io.Writer) error
+}
+
+// BinaryRunner runs commands by executing a terraform-compatible binary,
+// such as `terraform` or `tofu`.
+type BinaryRunner struct {
+	Binary string
+	// Dir is the working directory of the command. If empty, the current
+	// directory is used.
+	Dir string
+}
+
+func (r BinaryRunner) Run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	cmd := exec.CommandContext(ctx, r.Binary, args...)
+	cmd.Dir = r.Dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
+
+// Result is the outcome of running a single step.
+type Result struct {
+	Step     Step
+	Err      error
+	Duration time.Duration
+}
+
+// Recorder is told about each step before it runs and after it finishes, so
+// progress survives the process dying mid-migration.
+type Recorder interface {
+	Started(Step) error
+	Finished(Result) error
+}
+
+// Executor runs steps in order through a Runner, streaming their output.
+type Executor struct {
+	Runner Runner
+	Stdout io.Writer
+	Stderr io.Writer
+	// Recorder, if set, records every step. A step whose progress can't be
+	// recorded fails.
+	Recorder Recorder
+
+	// LockTimeout is passed to terraform as -lock-timeout so that a step waits
+	// for a state lock held by a concurrent step instead of failing.
+	LockTimeout time.Duration
+	// LockRetries is the number of times a step that failed to acquire the
+	// state lock is retried.
+	LockRetries int
+	// SerializeWrites runs at most one step at a time, for backends that can't
+	// handle concurrent writers even with locking.
+	SerializeWrites bool
+
+	writeMu sync.Mutex
+}
+
+// Run runs the steps in order and stops at the first failure. It returns a
+// result for every step that was started, including the one that failed.
+func (e *Executor) Run(ctx context.Context, steps []Step) ([]Result, error) {
+	results := make([]Result, 0, len(steps))
+	for _, s := range steps {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		r := e.runStep(ctx, s, e.stdout(), e.stderr())
+		results = append(results, r)
+		if r.Err != nil {
+			return results, fmt.Errorf("%s: %w", s, r.Err)
+		}
+	}
+	return results, nil
+}
+
+// lockErrorMessage is printed by terraform when the state lock is held elsewhere.
+const lockErrorMessage = "Error acquiring the state lock"
+
+// lockBackoff is how long to wait before the first retry of a locked step. Each
+// further retry waits one more lockBackoff.
+var lockBackoff = time.Second
+
+func (e *Executor) runStep(ctx context.Context, s Step, stdout, stderr io.Writer) Result {
+	if e.SerializeWrites {
+		e.writeMu.Lock()
+		defer e.writeMu.Unlock()
+	}
+
+	var flags []string
+	if e.LockTimeout > 0 {
+		flags = append(flags, fmt.Sprintf("-lock-timeout=%s", e.LockTimeout))
+	}
+	args := s.Args(flags...)
+
+	if e.Recorder != nil {
+		if err := e.Recorder.Started(s); err != nil {
+			return Result{Step: s, Err: fmt.Errorf("recording step: %w", err)}
+		}
+	}
+	r := e.attempt(ctx, s, args, stdout, stderr)
+	if e.Recorder != nil {
+		if err := e.Recorder.Finished(r); err != nil && r.Err == nil {
+			r.Err = fmt.Errorf("recording step: %w", err)
+		}
+	}
+	return r
+}
+
+// attempt runs the step, retrying while the state is locked.
+func (e *Executor) attempt(ctx context.Context, s Step, args []string, stdout, stderr io.Writer) Result {
+	start := time.Now()
+	var err error
+	for attempt := 0; ; attempt++ {
+		fmt.Fprintf(stderr, "==> %s\n", s)
+		var captured bytes.Buffer
+		err = e.Runner.Run(ctx, args, stdout, io.MultiWriter(stderr, &captured))
+		if err == nil || attempt >= e.LockRetries || !strings.Contains(captured.String(), lockErrorMessage) {
+			break
+		}
+		backoff := time.Duration(attempt+1) * lockBackoff
+		fmt.Fprintf(stderr, "state is locked, retrying %s in %s\n", s, backoff)
+		select {
+		case <-ctx.Done():
+			return Result{Step: s, Err: ctx.Err(), Duration: time.Since(start)}
+		case <-time.After(backoff):
+		}
+	}
+	return Result{Step: s, Err: err, Duration: time.Since(start)}
+}
+
+func (e *Executor) stdout() io.Writer {
+	if e.Stdout == nil {
+		return io.Discard
+	}
+	return e.Stdout
+}
+
+func (e *Executor) stderr() io.Writer {
+	if e.Stderr == nil {
+		return io.Discard
+	}
+	return e.Stderr
+}
+
+// Pull returns the current state as reported by `terraform state pull`.
+func Pull(ctx context.Context, runner Runner, stderr io.Writer) (state.V4, error) {
+	var out bytes.Buffer
+	if err := runner.Run(ctx, []string{"state", "pull"}, &out, stderr); err != nil {
+		return state.V4{}, fmt.Errorf("state pull: %w", err)
+	}
+	return state.Parse(out.Bytes())
+}
+
+// Summarize writes one line per result, followed by the steps that were never run.
+func Summarize(w io.Writer, steps []Step, results []Result) {
+	ran := make(map[Step]bool, len(results))
+	for _, r := range results {
+		ran[r.Step] = true
+		status := "ok"
+		if r.Err != nil {
+			status = "FAILED"
+		}
+		fmt.Fprintf(w, "%-7s %s (%s)\n", status, r.Step, r.Duration.Round(time.Millisecond))
+	}
+	for _, s := range steps {
+		if !ran[s] {
+			fmt.Fprintf(w, "%-7s %s\n", "skipped", s)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package execute
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+)
+
+type fakeRunner struct {
+	calls  [][]string
+	failOn string
+}
+
+func (f *fakeRunner) Run(_ context.Context, args []string, stdout, _ io.Writer) error {
+	f.calls = append(f.calls, args)
+	if strings.Join(args, " ") == f.failOn {
+		return errors.New("exit status 1")
+	}
+	_, err := io.WriteString(stdout, strings.Join(args, " ")+"\n")
+	return err
+}
+
+func TestPlan(t *testing.T) {
+	ordered := []*resources.Tuple{
+		{Type: "t", Name: "foo", ID: "foo-id", Tainted: true},
+		{Type: "t", Name: "bar", ID: "bar-id"},
+	}
+
+	for _, tt := range []struct {
+		name          string
+		includeRemove bool
+		want          []Step
+	}{{
+		name: "imports only",
+		want: []Step{
+			{Action: Import, Address: "t.foo", ID: "foo-id"},
+			{Action: Import, Address: "t.bar", ID: "bar-id"},
+			{Action: Taint, Address: "t.foo"},
+		},
+	}, {
+		name:          "removes in reverse order",
+		includeRemove: true,
+		want: []Step{
+			{Action: Remove, Address: "t.bar"},
+			{Action: Remove, Address: "t.foo"},
+			{Action: Import, Address: "t.foo", ID: "foo-id"},
+			{Action: Import, Address: "t.bar", ID: "bar-id"},
+			{Action: Taint, Address: "t.foo"},
+		},
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Plan(ordered, tt.includeRemove)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error("Plan() return mismatch (-want, +got):", diff)
+			}
+		})
+	}
+}
+
+func TestExecutorRun(t *testing.T) {
+	steps := []Step{
+		{Action: Remove, Address: "t.foo"},
+		{Action: Import, Address: "t.foo", ID: "foo-id"},
+		{Action: Import, Address: "t.bar", ID: "bar-id"},
+	}
+
+	for _, tt := range []struct {
+		name      string
+		failOn    string
+		wantCalls [][]string
+		wantErrs  []bool
+		wantOut   string
+	}{{
+		name: "all succeed",
+		wantCalls: [][]string{
+			{"state", "rm", "t.foo"},
+			{"import", "t.foo", "foo-id"},
+			{"import", "t.bar", "bar-id"},
+		},
+		wantErrs: []bool{false, false, false},
+		wantOut:  "state rm t.foo\nimport t.foo foo-id\nimport t.bar bar-id\n",
+	}, {
+		name:   "stops on first failure",
+		failOn: "import t.foo foo-id",
+		wantCalls: [][]string{
+			{"state", "rm", "t.foo"},
+			{"import", "t.foo", "foo-id"},
+		},
+		wantErrs: []bool{false, true},
+		wantOut:  "state rm t.foo\n",
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &fakeRunner{failOn: tt.failOn}
+			var stdout bytes.Buffer
+			e := Executor{Runner: runner, Stdout: &stdout}
+
+			results, err := e.Run(context.Background(), steps)
+			if (err != nil) != (tt.failOn != "") {
+				t.Errorf("Run() error = %v, want error: %t", err, tt.failOn != "")
+			}
+			if diff := cmp.Diff(tt.wantCalls, runner.calls); diff != "" {
+				t.Error("Run() calls mismatch (-want, +got):", diff)
+			}
+			gotErrs := make([]bool, len(results))
+			for i, r := range results {
+				gotErrs[i] = r.Err != nil
+			}
+			if diff := cmp.Diff(tt.wantErrs, gotErrs); diff != "" {
+				t.Error("Run() result errors mismatch (-want, +got):", diff)
+			}
+			if diff := cmp.Diff(tt.wantOut, stdout.String()); diff != "" {
+				t.Error("Run() output mismatch (-want, +got):", diff)
+			}
+		})
+	}
+}
+
+func TestBinaryRunner(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no shell available")
+	}
+
+	// A stub terraform that echoes its arguments and fails imports of t.bad.
+	stub := filepath.Join(t.TempDir(), "terraform")
+	script := "#!/bin/sh\n" +
+		"echo \"$@\"\n" +
+		"[ \"$2\" = t.bad ] && { echo failed >&2; exit 1; }\n" +
+		"exit 0\n"
+	if err := os.WriteFile(stub, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	e := Executor{Runner: BinaryRunner{Binary: stub}, Stdout: &stdout, Stderr: &stderr}
+	results, err := e.Run(context.Background(), []Step{
+		{Action: Import, Address: "t.good", ID: "good-id"},
+		{Action: Import, Address: "t.bad", ID: "bad-id"},
+		{Action: Import, Address: "t.never", ID: "never-id"},
+	})
+	if err == nil {
+		t.Fatal("Run() succeeded, want error")
+	}
+	if len(results) != 2 || results[0].Err != nil || results[1].Err == nil {
+		t.Errorf("Run() results = %+v, want success then failure", results)
+	}
+	if diff := cmp.Diff("import t.good good-id\nimport t.bad bad-id\n", stdout.String()); diff != "" {
+		t.Error("Run() output mismatch (-want, +got):", diff)
+	}
+	if !strings.Contains(stderr.String(), "failed") {
+		t.Errorf("Run() stderr = %q, want the stub's error output", stderr.String())
+	}
+}
+
+// lockedRunner fails with a lock error the first `locked` times it is run.
+type lockedRunner struct {
+	locked int
+	calls  [][]string
+}
+
+func (l *lockedRunner) Run(_ context.Context, args []string, _, stderr io.Writer) error {
+	l.calls = append(l.calls, args)
+	if len(l.calls) <= l.locked {
+		_, _ = io.WriteString(stderr, "Error: Error acquiring the state lock\n")
+		return errors.New("exit status 1")
+	}
+	return nil
+}
+
+func TestExecutorLockHandling(t *testing.T) {
+	lockBackoff = time.Millisecond
+	t.Cleanup(func() { lockBackoff = time.Second })
+
+	for _, tt := range []struct {
+		name      string
+		locked    int
+		retries   int
+		wantErr   bool
+		wantCalls int
+	}{{
+		name:      "no retries",
+		locked:    1,
+		wantErr:   true,
+		wantCalls: 1,
+	}, {
+		name:      "retried until unlocked",
+		locked:    2,
+		retries:   3,
+		wantCalls: 3,
+	}, {
+		name:      "retries exhausted",
+		locked:    5,
+		retries:   2,
+		wantErr:   true,
+		wantCalls: 3,
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &lockedRunner{locked: tt.locked}
+			e := Executor{Runner: runner, LockTimeout: 30 * time.Second, LockRetries: tt.retries}
+
+			_, err := e.Run(context.Background(), []Step{{Action: Remove, Address: "t.foo"}})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, want error: %t", err, tt.wantErr)
+			}
+			if len(runner.calls) != tt.wantCalls {
+				t.Errorf("Run() made %d calls, want %d", len(runner.calls), tt.wantCalls)
+			}
+			want := []string{"state", "rm", "-lock-timeout=30s", "t.foo"}
+			if diff := cmp.Diff(want, runner.calls[0]); diff != "" {
+				t.Error("Run() args mismatch (-want, +got):", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package execute
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// RunParallel runs the remove steps in order, then runs up to parallelism
+// import steps at a time, and finally the taint steps in order. An import only
+// starts once the imports of all of its dependencies, as given by deps (address
+// to dependency addresses), have succeeded. After the first failure no new
+// steps are started, but steps that are already running are allowed to finish.
+//
+// Output of concurrent steps is prefixed with the step's address so it can be
+// told apart.
+func (e *Executor) RunParallel(ctx context.Context, steps []Step, deps map[string][]string, parallelism int) ([]Result, error) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	var removes, imports, taints []Step
+	for _, s := range steps {
+		switch s.Action {
+		case Remove:
+			removes = append(removes, s)
+		case Taint:
+			taints = append(taints, s)
+		default:
+			imports = append(imports, s)
+		}
+	}
+
+	results, err := e.Run(ctx, removes)
+	if err != nil {
+		return results, err
+	}
+
+	planned := make(map[string]bool, len(imports))
+	for _, s := range imports {
+		planned[s.Address] = true
+	}
+	waiting := make([]int, len(imports))
+	dependents := make(map[string][]int, len(imports))
+	var ready []int
+	for i, s := range imports {
+		for _, d := range deps[s.Address] {
+			// Dependencies that aren't part of this plan were filtered out and
+			// are assumed to already be in state.
+			if !planned[d] || d == s.Address {
+				continue
+			}
+			waiting[i]++
+			dependents[d] = append(dependents[d], i)
+		}
+		if waiting[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	var outMu sync.Mutex
+	done := make(chan Result)
+	running := 0
+	var firstErr error
+	for {
+		for firstErr == nil && ctx.Err() == nil && len(ready) > 0 && running < parallelism {
+			s := imports[ready[0]]
+			ready = ready[1:]
+			running++
+			go func() {
+				stdout := newPrefixWriter(e.stdout(), &outMu, s.Address)
+				stderr := newPrefixWriter(e.stderr(), &outMu, s.Address)
+				r := e.runStep(ctx, s, stdout, stderr)
+				stdout.Flush()
+				stderr.Flush()
+				done <- r
+			}()
+		}
+		if running == 0 {
+			break
+		}
+
+		r := <-done
+		running--
+		results = append(results, r)
+		if r.Err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", r.Step, r.Err)
+			}
+			continue
+		}
+		for _, i := range dependents[r.Step.Address] {
+			waiting[i]--
+			if waiting[i] == 0 {
+				ready = append(ready, i)
+			}
+		}
+	}
+
+	if firstErr != nil {
+		return results, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+	if len(results) != len(removes)+len(imports) {
+		return results, errors.New("some imports were never ready to run, dependencies may contain a cycle")
+	}
+	tainted, err := e.Run(ctx, taints)
+	return append(results, tainted...), err
+}
+
+// prefixWriter prefixes each line written to it and writes whole lines to the
+// underlying writer while holding mu, so concurrent output doesn't interleave
+// within a line.
+type prefixWriter struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	prefix []byte
+	buf    bytes.Buffer
+}
+
+func newPrefixWriter(w io.Writer, mu *sync.Mutex, prefix string) *prefixWriter {
+	return &prefixWriter{w: w, mu: mu, prefix: []byte("[" + prefix + "] ")}
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf.Write(b)
+	for {
+		i := bytes.IndexByte(p.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		if err := p.writeLine(p.buf.Next(i + 1)); err != nil {
+			return len(b), err
+		}
+	}
+}
+
+// Flush writes any trailing partial line.
+func (p *prefixWriter) Flush() {
+	if p.buf.Len() > 0 {
+		_ = p.writeLine(append(p.buf.Next(p.buf.Len()), '\n'))
+	}
+}
+
+func (p *prefixWriter) writeLine(line []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := p.w.Write(append(append([]byte{}, p.prefix...), line...))
+	return err
+}
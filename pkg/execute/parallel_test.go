@@ -0,0 +1,201 @@
+package execute
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+)
+
+// recordingRunner records when each import started and finished, and the
+// most steps it saw running at once.
+type recordingRunner struct {
+	mu       sync.Mutex
+	running  int
+	max      int
+	started  map[string]int
+	finished map[string]int
+	clock    int
+	failOn   string
+}
+
+func (r *recordingRunner) Run(_ context.Context, args []string, stdout, _ io.Writer) error {
+	// Key removes separately so they aren't mistaken for the import starting.
+	address := args[len(args)-2]
+	if args[0] == "state" {
+		address = "rm " + args[len(args)-1]
+	}
+
+	r.mu.Lock()
+	r.clock++
+	r.started[address] = r.clock
+	r.running++
+	if r.running > r.max {
+		r.max = r.running
+	}
+	r.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+	_, _ = io.WriteString(stdout, "working\n")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clock++
+	r.finished[address] = r.clock
+	r.running--
+	if address == r.failOn {
+		return errors.New("exit status 1")
+	}
+	return nil
+}
+
+func newRecordingRunner(failOn string) *recordingRunner {
+	return &recordingRunner{
+		started:  map[string]int{},
+		finished: map[string]int{},
+		failOn:   failOn,
+	}
+}
+
+func TestExecutorRunParallel(t *testing.T) {
+	steps := []Step{
+		{Action: Remove, Address: "t.c"},
+		{Action: Import, Address: "t.a", ID: "a"},
+		{Action: Import, Address: "t.b", ID: "b"},
+		{Action: Import, Address: "t.c", ID: "c"},
+		{Action: Import, Address: "t.d", ID: "d"},
+	}
+	// c depends on a and b, d depends on c. t.filtered isn't in the plan.
+	deps := map[string][]string{
+		"t.c": {"t.a", "t.b"},
+		"t.d": {"t.c", "t.filtered"},
+	}
+
+	for _, tt := range []struct {
+		name        string
+		parallelism int
+		serialize   bool
+		failOn      string
+		wantMax     int
+		wantRun     []string
+		wantErr     bool
+	}{{
+		name:        "parallel",
+		parallelism: 4,
+		wantMax:     2,
+		wantRun:     []string{"t.a", "t.b", "t.c", "t.d"},
+	}, {
+		name:        "parallelism one",
+		parallelism: 1,
+		wantMax:     1,
+		wantRun:     []string{"t.a", "t.b", "t.c", "t.d"},
+	}, {
+		name:        "serialized writes",
+		parallelism: 4,
+		serialize:   true,
+		wantMax:     1,
+		wantRun:     []string{"t.a", "t.b", "t.c", "t.d"},
+	}, {
+		name:        "failure skips dependents",
+		parallelism: 4,
+		failOn:      "t.b",
+		wantMax:     2,
+		wantRun:     []string{"t.a", "t.b"},
+		wantErr:     true,
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := newRecordingRunner(tt.failOn)
+			var stdout bytes.Buffer
+			e := Executor{Runner: runner, Stdout: &stdout, SerializeWrites: tt.serialize}
+
+			results, err := e.RunParallel(context.Background(), steps, deps, tt.parallelism)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RunParallel() error = %v, want error: %t", err, tt.wantErr)
+			}
+			if runner.max != tt.wantMax {
+				t.Errorf("RunParallel() ran %d steps at once, want %d", runner.max, tt.wantMax)
+			}
+
+			var gotRun []string
+			for _, r := range results[1:] {
+				gotRun = append(gotRun, r.Step.Address)
+			}
+			sortStrings := cmpopts.SortSlices(func(a, b string) bool { return a < b })
+			if diff := cmp.Diff(tt.wantRun, gotRun, sortStrings); diff != "" {
+				t.Error("RunParallel() imported mismatch (-want, +got):", diff)
+			}
+
+			for address, ds := range deps {
+				if _, ok := runner.started[address]; !ok {
+					continue
+				}
+				for _, d := range ds {
+					if f, ok := runner.finished[d]; ok && f > runner.started[address] {
+						t.Errorf("%s started before its dependency %s finished", address, d)
+					}
+				}
+			}
+
+			// Removes run one at a time and aren't prefixed, imports are.
+			if got, want := strings.Count(stdout.String(), "] working\n"), len(results)-1; got != want {
+				t.Errorf("got %d prefixed output lines, want %d:\n%s", got, want, stdout.String())
+			}
+		})
+	}
+}
+
+func TestExecutorRunParallelTaint(t *testing.T) {
+	steps := []Step{
+		{Action: Import, Address: "t.a", ID: "a"},
+		{Action: Import, Address: "t.b", ID: "b"},
+		{Action: Taint, Address: "t.a"},
+	}
+	runner := &fakeRunner{}
+	e := Executor{Runner: runner}
+
+	results, err := e.RunParallel(context.Background(), steps, map[string][]string{"t.b": {"t.a"}}, 1)
+	if err != nil {
+		t.Fatalf("RunParallel() = %v", err)
+	}
+	want := [][]string{{"import", "t.a", "a"}, {"import", "t.b", "b"}, {"taint", "t.a"}}
+	if diff := cmp.Diff(want, runner.calls); diff != "" {
+		t.Error("RunParallel() calls mismatch (-want, +got):", diff)
+	}
+	if len(results) != len(steps) {
+		t.Errorf("RunParallel() returned %d results, want %d", len(results), len(steps))
+	}
+}
+
+func TestExecutorRunParallelCount(t *testing.T) {
+	// The instance depends on the `count` collection, and is only imported
+	// once each of its instances is.
+	rm := resources.ResourceMap{
+		"t.s[0]": {Type: "t", Name: "s", ID: "s0", IndexKey: float64(0)},
+		"t.s[1]": {Type: "t", Name: "s", ID: "s1", IndexKey: float64(1)},
+		"t.i":    {Type: "t", Name: "i", ID: "i", Dependencies: []string{"t.s"}},
+	}
+	ordered, err := rm.Order()
+	if err != nil {
+		t.Fatalf("Order() = %v", err)
+	}
+	runner := newRecordingRunner("")
+	e := Executor{Runner: runner, Stdout: io.Discard}
+
+	if _, err := e.RunParallel(context.Background(), Plan(ordered, false), rm.DependencyAddresses(), 4); err != nil {
+		t.Fatalf("RunParallel() = %v", err)
+	}
+	for _, d := range []string{"t.s[0]", "t.s[1]"} {
+		if runner.finished[d] > runner.started["t.i"] {
+			t.Errorf("t.i started before its dependency %s finished", d)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package explain
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/cmdpdx/tf-state-import/pkg/redact"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+)
+
+// Dependency is a dependency as recorded in state and the resources it
+// resolves to.
+type Dependency struct {
+	Recorded string
+	Resolved []string
+}
+
+// Explanation is everything that goes into importing a single resource.
+type Explanation struct {
+	Resource resources.Tuple
+	Provider string
+
+	ImportID string
+	Rule     resources.Rule
+
+	Dependencies []Dependency
+	// Dependents are the resources that depend on this one.
+	Dependents []string
+
+	// Position is the 1-based position of the resource in the import order.
+	// It is removed in the reverse order.
+	Position int
+	Total    int
+}
+
+// Explain explains how the resource at address in the resource map, built
+// from the state, is imported. The attributes of the explained resource are
+// redacted by the policy, after they've been used for its import ID.
+func Explain(st state.V4, rm resources.ResourceMap, address string, policy redact.Policy) (Explanation, error) {
+	r, ok := rm[address]
+	if !ok {
+		return Explanation{}, fmt.Errorf("%s is not an importable resource", address)
+	}
+	ordered, err := rm.Order()
+	if err != nil {
+		return Explanation{}, err
+	}
+
+	e := Explanation{
+		Resource: r,
+		Provider: provider(st, r),
+		Total:    len(ordered),
+	}
+	e.ImportID, e.Rule = r.ImportRule()
+	e.Resource.Attributes = policy.Attributes(r.Attributes, r.Sensitive)
+
+	for _, d := range r.Dependencies {
+		e.Dependencies = append(e.Dependencies, Dependency{Recorded: d, Resolved: rm.ResolveDependency(d)})
+	}
+	for a, deps := range rm.DependencyAddresses() {
+		for _, d := range deps {
+			if d == address {
+				e.Dependents = append(e.Dependents, a)
+				break
+			}
+		}
+	}
+	sort.Strings(e.Dependents)
+
+	for i, o := range ordered {
+		if o.Address() == address {
+			e.Position = i + 1
+		}
+	}
+	return e, nil
+}
+
+// provider returns the provider of the resource from the state.
+func provider(st state.V4, r resources.Tuple) string {
+	for _, sr := range st.Resources {
+		if sr.Mode != "data" && sr.Module == r.Module && sr.Type == r.Type && sr.Name == r.Name {
+			return sr.Provider
+		}
+	}
+	return ""
+}
+
+// Write writes the explanation in human readable form.
+func (e Explanation) Write(w io.Writer) error {
+	var err error
+	printf := func(format string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+
+	r := e.Resource
+	printf("address: %s\n", r.Address())
+	if r.Module != "" {
+		printf("  module:    %s\n", r.Module)
+	}
+	printf("  type:      %s\n", r.Type)
+	printf("  name:      %s\n", r.Name)
+	switch k := r.IndexKey.(type) {
+	case string:
+		printf("  index key: %q (for_each)\n", k)
+	case int, float64:
+		printf("  index key: %v (count)\n", k)
+	}
+	printf("provider: %s\n", e.Provider)
+
+	printf("\nimport id: %s\n", e.ImportID)
+	printf("  rule: %s\n", e.Rule.Name)
+	printf("  from attributes:\n")
+	for _, a := range e.Rule.Attributes {
+		v, ok := r.Attributes[a]
+		if !ok {
+			printf("    %s (missing)\n", a)
+			continue
+		}
+		printf("    %s = %s\n", a, redact.Format(v))
+	}
+
+	printf("\ndependencies:\n")
+	if len(e.Dependencies) == 0 {
+		printf("  (none)\n")
+	}
+	for _, d := range e.Dependencies {
+		switch {
+		case len(d.Resolved) == 0:
+			printf("  %s (not imported)\n", d.Recorded)
+		case len(d.Resolved) == 1 && d.Resolved[0] == d.Recorded:
+			printf("  %s\n", d.Recorded)
+		default:
+			printf("  %s\n", d.Recorded)
+			for _, a := range d.Resolved {
+				printf("    -> %s\n", a)
+			}
+		}
+	}
+
+	printf("\ndependents:\n")
+	if len(e.Dependents) == 0 {
+		printf("  (none)\n")
+	}
+	for _, a := range e.Dependents {
+		printf("  %s\n", a)
+	}
+
+	printf("\nplan position: imported %d of %d, removed %d of %d\n", e.Position, e.Total, e.Total-e.Position+1, e.Total)
+	return err
+}
@@ -0,0 +1,125 @@
+package explain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cmdpdx/tf-state-import/pkg/redact"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+)
+
+func TestExplain(t *testing.T) {
+	st, err := state.ParseStateFile("../state/testdata/example.tfstate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rm := resources.FromState(st, "")
+
+	e, err := Explain(st, rm, "module.api.google_monitoring_alert_policy.alert[0]", redact.Default)
+	if err != nil {
+		t.Fatalf("Explain() = %v", err)
+	}
+
+	var b strings.Builder
+	if err := e.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `address: module.api.google_monitoring_alert_policy.alert[0]
+  module:    module.api
+  type:      google_monitoring_alert_policy
+  name:      alert
+  index key: 0 (count)
+provider: provider["registry.terraform.io/hashicorp/google"]
+
+import id: projects/prod/alertPolicies/3257823384035534535
+  rule: default
+  from attributes:
+    id = "projects/prod/alertPolicies/3257823384035534535"
+
+dependencies:
+  chainguard_group.group
+  chainguard_identity.assumed-identity
+    -> chainguard_identity.assumed-identity["api"]
+    -> chainguard_identity.assumed-identity["build"]
+  module.api.module.this.module.this.google_project_iam_member.metrics-writer
+
+dependents:
+  (none)
+
+plan position: imported 6 of 8, removed 3 of 8
+`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Error("Write() output mismatch (-want, +got):", diff)
+	}
+}
+
+func TestExplainRuleAndDependents(t *testing.T) {
+	st, err := state.ParseStateFile("../state/testdata/example.tfstate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rm := resources.FromState(st, "")
+
+	e, err := Explain(st, rm, "chainguard_group.group", redact.Default)
+	if err != nil {
+		t.Fatalf("Explain() = %v", err)
+	}
+	want := []string{
+		"chainguard_group_invite.invite-code",
+		"chainguard_identity.assumed-identity[\"api\"]",
+		"chainguard_identity.assumed-identity[\"build\"]",
+		"module.api.google_monitoring_alert_policy.alert[0]",
+		"module.api.module.this.module.this.google_cloud_run_v2_service_iam_member.public-services-are-unauthenticated[\"us-central1\"]",
+	}
+	if diff := cmp.Diff(want, e.Dependents); diff != "" {
+		t.Error("Explain() dependents mismatch (-want, +got):", diff)
+	}
+
+	e, err = Explain(st, rm, "module.api.module.this.module.this.google_project_iam_member.metrics-writer", redact.Default)
+	if err != nil {
+		t.Fatalf("Explain() = %v", err)
+	}
+	if e.Rule.Name != "google_project_iam_member" || e.ImportID != "prod roles/monitoring.metricWriter serviceAccount:api@prod.iam.gserviceaccount.com" {
+		t.Errorf("Explain() rule = %s, import id = %q", e.Rule.Name, e.ImportID)
+	}
+
+	if _, err := Explain(st, rm, "data.chainguard_role.roles", redact.Default); err == nil {
+		t.Error("Explain() of a data source succeeded, want error")
+	}
+}
+
+func TestExplainRedacted(t *testing.T) {
+	rm := resources.ResourceMap{"t.x": {
+		Type:       "t",
+		Name:       "x",
+		ID:         "x",
+		Attributes: map[string]interface{}{"id": "x", "password": "p"},
+		Sensitive:  []string{"id"},
+	}}
+
+	e, err := Explain(state.V4{}, rm, "t.x", redact.Default)
+	if err != nil {
+		t.Fatalf("Explain() = %v", err)
+	}
+	if e.ImportID != "x" {
+		t.Errorf("Explain() import id = %q, want the unredacted x", e.ImportID)
+	}
+	want := map[string]interface{}{"id": redact.Marker, "password": redact.Marker}
+	if diff := cmp.Diff(want, e.Resource.Attributes); diff != "" {
+		t.Error("Explain() attributes mismatch (-want, +got):", diff)
+	}
+	if rm["t.x"].Attributes["password"] != "p" {
+		t.Error("Explain() changed the resource map")
+	}
+
+	var b strings.Builder
+	if err := e.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `    id = "(sensitive)"`) {
+		t.Errorf("Write() output doesn't redact the id:\n%s", b.String())
+	}
+}
@@ -0,0 +1,47 @@
+// Package files writes the files that commands generate without overwriting
+// existing ones by accident.
+package files
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// ExistError is the error for a file that already exists and wasn't
+// overwritten. It matches fs.ErrExist.
+type ExistError struct {
+	Path string
+}
+
+func (e *ExistError) Error() string {
+	return fmt.Sprintf("refusing to overwrite %s, use -force to overwrite", e.Path)
+}
+
+func (e *ExistError) Unwrap() error {
+	return fs.ErrExist
+}
+
+// Write writes data to the file at path, creating it with perm. An existing
+// file is only overwritten with force, otherwise Write returns an *ExistError
+// and leaves it untouched. The file is created exclusively, so a file that
+// appears while a command runs is never overwritten either.
+func Write(path string, data []byte, perm os.FileMode, force bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, perm)
+	if errors.Is(err, fs.ErrExist) {
+		return &ExistError{Path: path}
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
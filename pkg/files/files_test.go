@@ -0,0 +1,44 @@
+package files
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.tf")
+	read := func() string {
+		t.Helper()
+		bs, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(bs)
+	}
+
+	if err := Write(path, []byte("first, longer\n"), 0o644, false); err != nil {
+		t.Fatalf("Write() of a new file = %v", err)
+	}
+	if got := read(); got != "first, longer\n" {
+		t.Errorf("Write() wrote %q", got)
+	}
+
+	err := Write(path, []byte("second\n"), 0o644, false)
+	var exist *ExistError
+	if !errors.As(err, &exist) || exist.Path != path || !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Write() of an existing file = %v, want an *ExistError", err)
+	}
+	if got := read(); got != "first, longer\n" {
+		t.Errorf("Write() without force changed the file to %q", got)
+	}
+
+	if err := Write(path, []byte("second\n"), 0o644, true); err != nil {
+		t.Fatalf("Write() with force = %v", err)
+	}
+	if got := read(); got != "second\n" {
+		t.Errorf("Write() with force wrote %q, want the file replaced", got)
+	}
+}
@@ -0,0 +1,43 @@
+package hcl
+
+import (
+	"sort"
+	"strings"
+
+	hcl2 "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// RemoveBlocks returns src without the blocks at the ranges, which mustn't
+// overlap. A blank line after a removed block is removed with it, so removing
+// blocks doesn't leave runs of blank lines behind.
+func RemoveBlocks(src []byte, ranges []Range) []byte {
+	sorted := append([]Range(nil), ranges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	var out []byte
+	prev := 0
+	for _, r := range sorted {
+		out = append(out, src[prev:r.Start]...)
+		prev = r.End
+		if nl := strings.IndexByte(string(src[prev:]), '\n'); nl >= 0 && strings.TrimSpace(string(src[prev:prev+nl])) == "" {
+			prev += nl + 1
+		}
+	}
+	return append(out, src[prev:]...)
+}
+
+// Blank reports whether src has nothing but comments and whitespace, as a file
+// does once all of its blocks are removed.
+func Blank(src []byte) bool {
+	toks, diags := hclsyntax.LexConfig(src, "", hcl2.InitialPos)
+	if diags.HasErrors() {
+		return false
+	}
+	for _, t := range toks {
+		if t.Type != hclsyntax.TokenNewline && t.Type != hclsyntax.TokenComment && t.Type != hclsyntax.TokenEOF {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRemoveBlocks(t *testing.T) {
+	src := `# header
+
+import {
+  to = t.a
+  id = "a"
+}
+
+resource "t" "a" {}
+
+import {
+  to = t.b
+  id = "b"
+}
+`
+	m := &Module{}
+	if err := Parse("main.tf", []byte(src), m); err != nil {
+		t.Fatal(err)
+	}
+	var ranges []Range
+	for i := len(m.Imports) - 1; i >= 0; i-- {
+		ranges = append(ranges, m.Imports[i].Range)
+	}
+
+	got := string(RemoveBlocks([]byte(src), ranges))
+	want := `# header
+
+resource "t" "a" {}
+
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("RemoveBlocks() return mismatch (-want, +got):", diff)
+	}
+}
+
+func TestBlank(t *testing.T) {
+	for _, tt := range []struct {
+		src  string
+		want bool
+	}{
+		{"", true},
+		{"# Generated\n\n/* block\ncomment */\n// line\n", true},
+		{"# Generated\nimport {\n}\n", false},
+		{"locals {\n  a = \"x\n}\n", false},
+	} {
+		if got := Blank([]byte(tt.src)); got != tt.want {
+			t.Errorf("Blank(%q) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package hcl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Expansion is how a resource or module block is repeated.
+type Expansion int
+
+const (
+	// Single blocks have a single instance, without an index key.
+	Single Expansion = iota
+	// Count blocks have instances with number keys.
+	Count
+	// ForEach blocks have instances with string keys.
+	ForEach
+)
+
+func (e Expansion) String() string {
+	switch e {
+	case Count:
+		return "count"
+	case ForEach:
+		return "for_each"
+	default:
+		return "neither count nor for_each"
+	}
+}
+
+// Pos is where a block is declared.
+type Pos struct {
+	File string
+	Line int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%s:%d", p.File, p.Line)
+}
+
+// Resource is a resource or data block.
+type Resource struct {
+	Mode      string
+	Type      string
+	Name      string
+	Expansion Expansion
+	Pos       Pos
+}
+
+// ModuleCall is a module block.
+type ModuleCall struct {
+	Name      string
+	Source    string
+	Expansion Expansion
+	Pos       Pos
+	// Module is the called module, if its source is a local directory.
+	Module *Module
+}
+
+// Local reports whether the module source is a local directory, which is the
+// only kind of module that is loaded.
+func (c ModuleCall) Local() bool {
+	return strings.HasPrefix(c.Source, "./") || strings.HasPrefix(c.Source, "../")
+}
+
+// Range is the source of a block in its file, from the start of the line it
+// begins on up to and including the newline after its closing brace.
+type Range struct {
+	Start, End int
+}
+
+// ImportBlock is an import block.
+type ImportBlock struct {
+	// To is the source of the address expression, e.g. `t.name["a"]`, or
+	// `t.name[each.key]` in blocks with for_each.
+	To string
+	// ID is the import ID if it's a literal string, otherwise IDExpression is
+	// the source of its expression.
+	ID           string
+	IDExpression string
+	ForEach      bool
+	Pos          Pos
+	Range        Range
+}
+
+// RemovedBlock is a removed block.
+type RemovedBlock struct {
+	// From is the source of the address of the removed resource or module.
+	From  string
+	Pos   Pos
+	Range Range
+}
+
+// MovedBlock is a moved block.
+type MovedBlock struct {
+	// From and To are the sources of the addresses, relative to the module
+	// the block is in.
+	From string
+	To   string
+	Pos  Pos
+}
+
+// Module is the configuration in a directory.
+type Module struct {
+	Dir       string
+	Resources []Resource
+	Calls     []ModuleCall
+	Imports   []ImportBlock
+	Removed   []RemovedBlock
+	Moved     []MovedBlock
+}
+
+// LoadModule parses the .tf and .tf.json files in dir, and the local modules
+// it calls.
+func LoadModule(dir string) (*Module, error) {
+	return loadModule(dir, nil)
+}
+
+func loadModule(dir string, parents []string) (*Module, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range parents {
+		if p == abs {
+			return nil, fmt.Errorf("%s: module calls itself", dir)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	jsonFiles, err := filepath.Glob(filepath.Join(dir, "*.tf.json"))
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, jsonFiles...)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s: no .tf or .tf.json files found", dir)
+	}
+	sort.Strings(files)
+
+	m := &Module{Dir: dir}
+	for _, f := range files {
+		// Override files change existing blocks, they don't declare new ones.
+		if base := strings.TrimSuffix(filepath.Base(f), ".json"); base == "override.tf" || strings.HasSuffix(base, "_override.tf") {
+			continue
+		}
+		src, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if err := Parse(f, src, m); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, c := range m.Calls {
+		if !c.Local() {
+			continue
+		}
+		if m.Calls[i].Module, err = loadModule(filepath.Join(dir, c.Source), append(parents, abs)); err != nil {
+			return nil, fmt.Errorf("module %s: %w", c.Name, err)
+		}
+	}
+	return m, nil
+}
+
+// Step is a module call or resource in an address, with its index key.
+type Step struct {
+	Name string
+	// Key is the index key, a number or a quoted string, without brackets.
+	Key string
+}
+
+// Expansion returns the expansion that the step's key implies.
+func (s Step) Expansion() Expansion {
+	switch {
+	case s.Key == "":
+		return Single
+	case strings.HasPrefix(s.Key, `"`):
+		return ForEach
+	default:
+		return Count
+	}
+}
+
+// Address is a parsed resource instance address.
+type Address struct {
+	Modules []Step
+	Type    string
+	// Resource is the resource name and index key.
+	Resource Step
+}
+
+// ParseAddress parses a resource instance address such as
+// `module.a["x"].module.b.t.name[0]`.
+func ParseAddress(address string) (Address, error) {
+	var a Address
+	rest := address
+	for strings.HasPrefix(rest, "module.") {
+		s, r, err := parseStep(rest[len("module."):])
+		if err != nil {
+			return Address{}, fmt.Errorf("invalid address %s: %w", address, err)
+		}
+		a.Modules = append(a.Modules, s)
+		if !strings.HasPrefix(r, ".") {
+			return Address{}, fmt.Errorf("invalid address %s: expected a resource after module %s", address, s.Name)
+		}
+		rest = r[1:]
+	}
+
+	typ, rest, ok := strings.Cut(rest, ".")
+	if !ok || typ == "" || typ == "data" {
+		return Address{}, fmt.Errorf("invalid address %s: expected a managed resource", address)
+	}
+	s, rest, err := parseStep(rest)
+	if err != nil {
+		return Address{}, fmt.Errorf("invalid address %s: %w", address, err)
+	}
+	if rest != "" {
+		return Address{}, fmt.Errorf("invalid address %s: unexpected %q", address, rest)
+	}
+	a.Type, a.Resource = typ, s
+	return a, nil
+}
+
+// parseStep parses a name and optional index key from the start of s.
+func parseStep(s string) (Step, string, error) {
+	i := 0
+	for i < len(s) && s[i] != '.' && s[i] != '[' {
+		i++
+	}
+	step := Step{Name: s[:i]}
+	if step.Name == "" {
+		return Step{}, "", fmt.Errorf("missing name")
+	}
+	if i == len(s) || s[i] != '[' {
+		return step, s[i:], nil
+	}
+
+	end := -1
+	inQuote := false
+	for j := i + 1; j < len(s) && end < 0; j++ {
+		switch {
+		case inQuote && s[j] == '\\':
+			j++
+		case s[j] == '"':
+			inQuote = !inQuote
+		case !inQuote && s[j] == ']':
+			end = j
+		}
+	}
+	if end < 0 {
+		return Step{}, "", fmt.Errorf("unterminated index key")
+	}
+	step.Key = s[i+1 : end]
+	return step, s[end+1:], nil
+}
+
+// ConfigAddress is the address of the block that declares the resource, e.g.
+// `module.a.module.b.t.name`.
+func (a Address) ConfigAddress() string {
+	var parts []string
+	for _, m := range a.Modules {
+		parts = append(parts, "module."+m.Name)
+	}
+	return strings.Join(append(parts, a.Type, a.Resource.Name), ".")
+}
+
+func (s Step) String() string {
+	if s.Key == "" {
+		return s.Name
+	}
+	return s.Name + "[" + s.Key + "]"
+}
+
+// MovedTo returns the address that the moved blocks of the configuration move
+// the resource instance at address to, following chains of moves, and the
+// last moved block that applies. It reports false if no moved block applies.
+func (m *Module) MovedTo(address string) (string, MovedBlock, bool) {
+	var last MovedBlock
+	// Every block applies at most once, which also ends cycles.
+	seen := map[MovedBlock]bool{}
+	for {
+		to, b, ok := m.move(address, seen)
+		if !ok {
+			return address, last, len(seen) > 0
+		}
+		seen[b] = true
+		address, last = to, b
+	}
+}
+
+// move applies the first moved block, in the module at any level of address,
+// whose from address is or contains the address.
+func (m *Module) move(address string, seen map[MovedBlock]bool) (string, MovedBlock, bool) {
+	a, err := ParseAddress(address)
+	if err != nil {
+		return "", MovedBlock{}, false
+	}
+	module := m
+	prefix := ""
+	for i := 0; module != nil; i++ {
+		var rest []string
+		for _, s := range a.Modules[i:] {
+			rest = append(rest, "module."+s.String())
+		}
+		relative := strings.Join(append(rest, a.Type+"."+a.Resource.String()), ".")
+		for _, b := range module.Moved {
+			if seen[b] || b.From == "" || b.To == "" {
+				continue
+			}
+			if relative == b.From || strings.HasPrefix(relative, b.From+".") || strings.HasPrefix(relative, b.From+"[") {
+				return prefix + b.To + strings.TrimPrefix(relative, b.From), b, true
+			}
+		}
+		if i == len(a.Modules) {
+			break
+		}
+		prefix += "module." + a.Modules[i].String() + "."
+		next := module
+		module = nil
+		for _, c := range next.Calls {
+			if c.Name == a.Modules[i].Name {
+				module = c.Module
+			}
+		}
+	}
+	return "", MovedBlock{}, false
+}
@@ -0,0 +1,164 @@
+package hcl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadModule(t *testing.T) {
+	got, err := LoadModule("testdata/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	main := filepath.Join("testdata/config", "main.tf")
+	events := filepath.Join("testdata/config", "events.tf.json")
+	service := filepath.Join("testdata/config", "modules/service", "main.tf")
+	want := &Module{
+		Dir: "testdata/config",
+		Resources: []Resource{
+			{Mode: "managed", Type: "google_pubsub_topic", Name: "events", Pos: Pos{events, 4}},
+			{Mode: "managed", Type: "google_project_iam_member", Name: "metrics-writer", Pos: Pos{main, 21}},
+			{Mode: "managed", Type: "google_service_account", Name: "api", Pos: Pos{main, 27}},
+			{Mode: "managed", Type: "google_storage_bucket", Name: "logs", Expansion: Count, Pos: Pos{main, 32}},
+			{Mode: "data", Type: "google_project", Name: "this", Pos: Pos{main, 42}},
+		},
+		Calls: []ModuleCall{{
+			Name:      "service",
+			Source:    "./modules/service",
+			Expansion: ForEach,
+			Pos:       Pos{main, 44},
+			Module: &Module{
+				Dir: filepath.Join("testdata/config", "modules/service"),
+				Resources: []Resource{
+					{Mode: "managed", Type: "google_cloud_run_v2_service", Name: "this", Expansion: Count, Pos: Pos{service, 3}},
+					{Mode: "managed", Type: "google_cloud_run_v2_service_iam_member", Name: "invoker", Expansion: ForEach, Pos: Pos{service, 5}},
+				},
+			},
+		}, {
+			Name:   "remote",
+			Source: "terraform-google-modules/network/google",
+			Pos:    Pos{main, 50},
+		}},
+		Moved: []MovedBlock{{From: "google_storage_bucket.archive", To: "google_storage_bucket.logs", Pos: Pos{events, 9}}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("LoadModule() return mismatch (-want, +got):", diff)
+	}
+
+	if _, err := LoadModule(t.TempDir()); err == nil {
+		t.Error("LoadModule() of a directory without configuration succeeded")
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	for _, tt := range []struct {
+		address string
+		want    Address
+		config  string
+		wantErr bool
+	}{{
+		address: "t.name",
+		want:    Address{Type: "t", Resource: Step{Name: "name"}},
+		config:  "t.name",
+	}, {
+		address: `module.a["x.y]"].module.b[0].t.name["k"]`,
+		want: Address{
+			Modules:  []Step{{Name: "a", Key: `"x.y]"`}, {Name: "b", Key: "0"}},
+			Type:     "t",
+			Resource: Step{Name: "name", Key: `"k"`},
+		},
+		config: "module.a.module.b.t.name",
+	}, {
+		address: "data.t.name",
+		wantErr: true,
+	}, {
+		address: "module.a",
+		wantErr: true,
+	}, {
+		address: `t.name["k"`,
+		wantErr: true,
+	}, {
+		address: "t.name[0].extra",
+		wantErr: true,
+	}} {
+		t.Run(tt.address, func(t *testing.T) {
+			got, err := ParseAddress(tt.address)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAddress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error("ParseAddress() return mismatch (-want, +got):", diff)
+			}
+			if c := got.ConfigAddress(); c != tt.config {
+				t.Errorf("ConfigAddress() = %q, want %q", c, tt.config)
+			}
+		})
+	}
+}
+
+func TestMovedTo(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.tf": `moved {
+  from = t.old
+  to   = t.renamed
+}
+moved {
+  from = t.renamed
+  to   = module.app.t.new
+}
+moved {
+  from = module.legacy
+  to   = module.app
+}
+module "app" {
+  source   = "./app"
+  for_each = var.apps
+}
+`,
+		"loop.tf.json": `{"moved": [{"from": "t.a", "to": "t.b"}, {"from": "t.b", "to": "t.a"}]}`,
+		"app/main.tf": `moved {
+  from = t.inner_old
+  to   = t.inner[0]
+}
+`,
+	}
+	for name, src := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root, err := LoadModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		address string
+		want    string
+		wantOK  bool
+	}{
+		{"t.old", "module.app.t.new", true},
+		{"t.old[1]", "module.app.t.new[1]", true},
+		{`module.legacy["x"].t.y`, `module.app["x"].t.y`, true},
+		{`module.app["x"].t.inner_old`, `module.app["x"].t.inner[0]`, true},
+		{"t.a", "t.a", true},
+		{"t.older", "t.older", false},
+		{`module.app["x"].t.old`, `module.app["x"].t.old`, false},
+	} {
+		got, _, ok := root.MovedTo(tt.address)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("MovedTo(%s) = %s, %v, want %s, %v", tt.address, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
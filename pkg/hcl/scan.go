@@ -0,0 +1,161 @@
+package hcl
+
+import (
+	"strings"
+
+	hcl2 "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/json"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// The configuration is parsed with HCL, in its native or JSON syntax, but only
+// the top-level resource, data, module, import, removed and moved blocks and the
+// few arguments of them that decide or refer to addresses are read. Nothing is
+// evaluated.
+
+// topLevel are the blocks that are read from a configuration file.
+var topLevel = &hcl2.BodySchema{
+	Blocks: []hcl2.BlockHeaderSchema{
+		{Type: "resource", LabelNames: []string{"type", "name"}},
+		{Type: "data", LabelNames: []string{"type", "name"}},
+		{Type: "module", LabelNames: []string{"name"}},
+		{Type: "import"},
+		{Type: "removed"},
+		{Type: "moved"},
+	},
+}
+
+// arguments are the arguments read from each kind of block.
+var arguments = map[string]*hcl2.BodySchema{
+	"resource": {Attributes: []hcl2.AttributeSchema{{Name: "count"}, {Name: "for_each"}}},
+	"data":     {Attributes: []hcl2.AttributeSchema{{Name: "count"}, {Name: "for_each"}}},
+	"module":   {Attributes: []hcl2.AttributeSchema{{Name: "source"}, {Name: "count"}, {Name: "for_each"}}},
+	"import":   {Attributes: []hcl2.AttributeSchema{{Name: "to"}, {Name: "id"}, {Name: "for_each"}}},
+	"removed":  {Attributes: []hcl2.AttributeSchema{{Name: "from"}}},
+	"moved":    {Attributes: []hcl2.AttributeSchema{{Name: "from"}, {Name: "to"}}},
+}
+
+// Parse parses the source of a .tf or .tf.json file and adds its resources,
+// module calls, import, removed and moved blocks to m.
+func Parse(filename string, src []byte, m *Module) error {
+	isJSON := strings.HasSuffix(filename, ".json")
+	var (
+		file  *hcl2.File
+		diags hcl2.Diagnostics
+	)
+	if isJSON {
+		file, diags = json.Parse(src, filename)
+	} else {
+		file, diags = hclsyntax.ParseConfig(src, filename, hcl2.InitialPos)
+	}
+	if diags.HasErrors() {
+		return diags
+	}
+	content, _, diags := file.Body.PartialContent(topLevel)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	// Only the native syntax has the ranges of whole blocks, for removing
+	// them. Blocks are found by the start of their type keyword.
+	ends := map[int]int{}
+	if body, ok := file.Body.(*hclsyntax.Body); ok {
+		for _, b := range body.Blocks {
+			end := b.Range().End.Byte
+			if end < len(src) && src[end] == '\n' {
+				end++
+			}
+			ends[b.TypeRange.Start.Byte] = end
+		}
+	}
+
+	for _, b := range content.Blocks {
+		args, _, diags := b.Body.PartialContent(arguments[b.Type])
+		if diags.HasErrors() {
+			return diags
+		}
+		attrs := args.Attributes
+		pos := Pos{File: filename, Line: b.DefRange.Start.Line}
+		var r Range
+		if end, ok := ends[b.TypeRange.Start.Byte]; ok {
+			r = Range{Start: lineOffset(src, b.TypeRange.Start.Byte), End: end}
+		}
+		source := func(name string) string {
+			a, ok := attrs[name]
+			if !ok {
+				return ""
+			}
+			return expressionSource(a.Expr, src, isJSON)
+		}
+
+		switch b.Type {
+		case "resource", "data":
+			mode := "managed"
+			if b.Type == "data" {
+				mode = "data"
+			}
+			m.Resources = append(m.Resources, Resource{Mode: mode, Type: b.Labels[0], Name: b.Labels[1], Expansion: expansion(attrs), Pos: pos})
+		case "module":
+			c := ModuleCall{Name: b.Labels[0], Expansion: expansion(attrs), Pos: pos}
+			if a, ok := attrs["source"]; ok {
+				c.Source, _ = literal(a.Expr)
+			}
+			m.Calls = append(m.Calls, c)
+		case "import":
+			imp := ImportBlock{To: source("to"), Pos: pos, Range: r}
+			if a, ok := attrs["id"]; ok {
+				var isLiteral bool
+				if imp.ID, isLiteral = literal(a.Expr); !isLiteral {
+					imp.IDExpression = source("id")
+				}
+			}
+			_, imp.ForEach = attrs["for_each"]
+			m.Imports = append(m.Imports, imp)
+		case "removed":
+			m.Removed = append(m.Removed, RemovedBlock{From: source("from"), Pos: pos, Range: r})
+		case "moved":
+			m.Moved = append(m.Moved, MovedBlock{From: source("from"), To: source("to"), Pos: pos})
+		}
+	}
+	return nil
+}
+
+func expansion(attrs hcl2.Attributes) Expansion {
+	switch {
+	case attrs["count"] != nil:
+		return Count
+	case attrs["for_each"] != nil:
+		return ForEach
+	}
+	return Single
+}
+
+// literal returns the value of a string expression that refers to nothing.
+func literal(expr hcl2.Expression) (string, bool) {
+	if len(expr.Variables()) > 0 {
+		return "", false
+	}
+	v, diags := expr.Value(nil)
+	if diags.HasErrors() || !v.IsKnown() || v.IsNull() || v.Type() != cty.String {
+		return "", false
+	}
+	return v.AsString(), true
+}
+
+// expressionSource returns the source of the expression. In the JSON syntax
+// addresses are strings, their value is their source.
+func expressionSource(expr hcl2.Expression, src []byte, isJSON bool) string {
+	if isJSON {
+		if s, ok := literal(expr); ok {
+			return s
+		}
+	}
+	r := expr.Range()
+	return strings.TrimSpace(string(src[r.Start.Byte:r.End.Byte]))
+}
+
+// lineOffset returns the offset of the start of the line containing offset.
+func lineOffset(src []byte, offset int) int {
+	return strings.LastIndexByte(string(src[:offset]), '\n') + 1
+}
@@ -0,0 +1,175 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseJSON(t *testing.T) {
+	src := `{
+  "resource": {
+    "t": {
+      "single": {},
+      "counted": {"count": 2}
+    }
+  },
+  "module": {"m": {"source": "./m", "for_each": "${var.xs}"}},
+  "import": [
+    {"to": "t.single", "id": "s-1"},
+    {"to": "t.counted[each.key]", "id": "${each.value}", "for_each": "${var.ids}"}
+  ],
+  "moved": {"from": "t.old", "to": "t.single"}
+}`
+	got := &Module{}
+	if err := Parse("main.tf.json", []byte(src), got); err != nil {
+		t.Fatal(err)
+	}
+	want := &Module{
+		Resources: []Resource{
+			{Mode: "managed", Type: "t", Name: "single", Pos: Pos{"main.tf.json", 4}},
+			{Mode: "managed", Type: "t", Name: "counted", Expansion: Count, Pos: Pos{"main.tf.json", 5}},
+		},
+		Calls: []ModuleCall{{Name: "m", Source: "./m", Expansion: ForEach, Pos: Pos{"main.tf.json", 8}}},
+		Imports: []ImportBlock{
+			{To: "t.single", ID: "s-1", Pos: Pos{"main.tf.json", 9}},
+			{To: "t.counted[each.key]", IDExpression: `"${each.value}"`, ForEach: true, Pos: Pos{"main.tf.json", 9}},
+		},
+		Moved: []MovedBlock{{From: "t.old", To: "t.single", Pos: Pos{"main.tf.json", 13}}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("Parse() return mismatch (-want, +got):", diff)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		src     string
+		want    *Module
+		wantErr bool
+	}{{
+		name: "blocks",
+		src: `resource "t" "single" {}
+resource "t" "counted" {
+  count = 2
+}
+resource t for_each { for_each = {} }
+data "t" "d" {
+  count = 1
+}
+module "m" {
+  source = "./m"
+  for_each = {
+    a = 1
+  }
+}
+`,
+		want: &Module{
+			Resources: []Resource{
+				{Mode: "managed", Type: "t", Name: "single", Pos: Pos{"main.tf", 1}},
+				{Mode: "managed", Type: "t", Name: "counted", Expansion: Count, Pos: Pos{"main.tf", 2}},
+				{Mode: "managed", Type: "t", Name: "for_each", Expansion: ForEach, Pos: Pos{"main.tf", 5}},
+				{Mode: "data", Type: "t", Name: "d", Expansion: Count, Pos: Pos{"main.tf", 6}},
+			},
+			Calls: []ModuleCall{{Name: "m", Source: "./m", Expansion: ForEach, Pos: Pos{"main.tf", 9}}},
+		},
+	}, {
+		name: "nested arguments don't count",
+		src: `resource "t" "r" {
+  dynamic "x" {
+    for_each = var.xs
+  }
+  tags = {
+    count = 1
+  }
+  list = [
+    count
+  ]
+}
+`,
+		want: &Module{Resources: []Resource{{Mode: "managed", Type: "t", Name: "r", Pos: Pos{"main.tf", 1}}}},
+	}, {
+		name: "strings, comments and heredocs",
+		src: `# resource "t" "hash" {}
+// resource "t" "slashes" {}
+/* resource "t" "block" {
+} */
+locals {
+  a = "}{ ${"\"}"} %{ if true }{%{ endif }"
+  b = <<EOT
+resource "t" "heredoc" {
+EOT
+}
+resource "t" "after" {
+  name = "$${literal} ${var.x}"
+}
+`,
+		want: &Module{Resources: []Resource{{Mode: "managed", Type: "t", Name: "after", Pos: Pos{"main.tf", 11}}}},
+	}, {
+		name: "import and removed blocks",
+		src: `import {
+  to = t.a["x"]
+  id = "x-1"
+}
+
+import {
+  for_each = var.ids
+  to       = module.m.t.b[each.key]
+  id       = each.value
+}
+removed {
+  from = module.old
+  lifecycle { destroy = false }
+}
+`,
+		want: &Module{
+			Imports: []ImportBlock{
+				{To: `t.a["x"]`, ID: "x-1", Pos: Pos{"main.tf", 1}, Range: Range{0, 40}},
+				{To: "module.m.t.b[each.key]", IDExpression: "each.value", ForEach: true, Pos: Pos{"main.tf", 6}, Range: Range{41, 133}},
+			},
+			Removed: []RemovedBlock{{From: "module.old", Pos: Pos{"main.tf", 11}, Range: Range{133, 197}}},
+		},
+	}, {
+		name: "moved blocks and escaped ids",
+		src: `moved {
+  from = t.old
+  to   = module.m.t.new
+}
+import {
+  to = t.a
+  id = "a\"b $${x}"
+}
+`,
+		want: &Module{
+			Imports: []ImportBlock{{To: "t.a", ID: `a"b ${x}`, Pos: Pos{"main.tf", 5}, Range: Range{49, 91}}},
+			Moved:   []MovedBlock{{From: "t.old", To: "module.m.t.new", Pos: Pos{"main.tf", 1}}},
+		},
+	}, {
+		name:    "unterminated block",
+		src:     `resource "t" "r" {`,
+		wantErr: true,
+	}, {
+		name:    "unterminated string",
+		src:     "locals {\n  a = \"x\n}\n",
+		wantErr: true,
+	}, {
+		name:    "unexpected brace",
+		src:     "}",
+		wantErr: true,
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := &Module{}
+			err := Parse("main.tf", []byte(tt.src), got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error("Parse() return mismatch (-want, +got):", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+terraform {
+  required_version = ">= 1.7"
+}
+
+# resource "commented" "out" {}
+/*
+resource "block" "comment" {}
+*/
+
+locals {
+  services = {
+    api = { port = 8080 }
+  }
+  script = <<-EOT
+    resource "in" "heredoc" {
+      count = 2
+    }
+  EOT
+}
+
+resource "google_project_iam_member" "metrics-writer" {
+  project = "prod"
+  role    = "roles/monitoring.metricWriter"
+  member  = "serviceAccount:${google_service_account.api.email}"
+}
+
+resource "google_service_account" "api" {
+  account_id   = "api"
+  display_name = "API ${"}"} {"
+}
+
+resource google_storage_bucket "logs" {
+  count = var.enabled ? 1 : 0
+  name  = "logs"
+
+  dynamic "lifecycle_rule" {
+    for_each = var.rules
+    content {}
+  }
+}
+
+data "google_project" "this" {}
+
+module "service" {
+  source   = "./modules/service"
+  for_each = local.services
+  name     = each.key
+}
+
+module "remote" {
+  source = "terraform-google-modules/network/google"
+}
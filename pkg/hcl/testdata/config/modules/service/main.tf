@@ -0,0 +1,10 @@
+variable "name" {}
+
+resource "google_cloud_run_v2_service" "this" { count = 1 }
+
+resource "google_cloud_run_v2_service_iam_member" "invoker" {
+  for_each = toset(["allUsers"])
+  name     = google_cloud_run_v2_service.this[0].name
+  role     = "roles/run.invoker"
+  member   = each.value
+}
@@ -0,0 +1,230 @@
+package hcl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/maps"
+
+	"github.com/cmdpdx/tf-state-import/pkg/redact"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+)
+
+// IgnoredAttributes are the computed attributes that aren't written to the
+// generated configuration, by resource type. The attributes under "*" are
+// ignored for every type. Attributes of nested blocks are given by their path,
+// e.g. `rule.id`.
+var IgnoredAttributes = map[string][]string{
+	"*": {"id", "etag", "self_link", "creation_timestamp", "effective_labels", "terraform_labels", "timeouts"},
+	"google_cloud_run_v2_service": {
+		"uid",
+		"generation",
+		"observed_generation",
+		"create_time",
+		"update_time",
+		"creator",
+		"last_modifier",
+		"uri",
+		"reconciling",
+		"conditions",
+		"terminal_condition",
+		"latest_ready_revision",
+		"latest_created_revision",
+	},
+	"google_compute_backend_service": {
+		"fingerprint",
+		"generated_id",
+	},
+	"google_monitoring_alert_policy": {
+		"name",
+		"creation_record",
+		"conditions.name",
+	},
+	"google_secret_manager_secret": {
+		"name",
+		"create_time",
+	},
+	"google_storage_bucket": {
+		"url",
+	},
+}
+
+// Configurable reports whether a resource block can be written for the
+// resource at address: configuration can only be generated for single
+// resources in the root module, like `terraform plan -generate-config-out`.
+func Configurable(address string) bool {
+	return !strings.HasPrefix(address, "module.") && !strings.HasSuffix(address, "]")
+}
+
+// WriteResources writes a resource block for each resource, at the address
+// given by rewrite. Resources in modules and collections are listed in a
+// comment instead, their configuration has to be written by hand. Values the
+// policy redacts are written as null, with a comment. It returns the number of
+// resource blocks written.
+func WriteResources(w io.Writer, ordered []*resources.Tuple, rewrite func(string) string, policy redact.Policy) (int, error) {
+	var skipped []string
+	n := 0
+	for _, r := range ordered {
+		address := rewrite(r.Address())
+		if !Configurable(address) {
+			skipped = append(skipped, address)
+			continue
+		}
+		typ, name, _ := strings.Cut(address, ".")
+		if _, err := fmt.Fprintf(w, "%s\n", ResourceBlock(typ, name, policy.Attributes(r.Attributes, r.Sensitive))); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if len(skipped) > 0 {
+		lines := []string{"# No configuration was generated for these resources in modules or collections:"}
+		for _, a := range skipped {
+			lines = append(lines, "#   "+a)
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(lines, "\n")); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// ResourceBlock returns a best-effort resource block with the attributes from
+// state. Null and empty values and the ignored attributes of the type are
+// left out, and lists of objects are written as nested blocks. Attributes
+// holding redact.Marker are written as null, to be filled in by hand.
+func ResourceBlock(typ, name string, attributes map[string]interface{}) string {
+	ignored := map[string]bool{}
+	for _, t := range []string{"*", typ} {
+		for _, a := range IgnoredAttributes[t] {
+			ignored[a] = true
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "resource %s %s {\n", state.Quote(typ), state.Quote(name))
+	writeBody(&b, attributes, "", ignored, 1)
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func writeBody(b *strings.Builder, attributes map[string]interface{}, path string, ignored map[string]bool, depth int) {
+	indent := strings.Repeat("  ", depth)
+	keys := maps.Keys(attributes)
+	sort.Strings(keys)
+
+	// Attributes first, then nested blocks, as terraform fmt would have it.
+	var blocks []string
+	for _, k := range keys {
+		v := attributes[k]
+		if ignored[path+k] || isEmpty(v) || !state.IsIdentifier(k) {
+			continue
+		}
+		if isBlockList(v) {
+			blocks = append(blocks, k)
+			continue
+		}
+		if redacted(v) {
+			fmt.Fprintf(b, "%s%s = null # sensitive\n", indent, k)
+			continue
+		}
+		fmt.Fprintf(b, "%s%s = %s\n", indent, k, value(v, depth))
+	}
+	for _, k := range blocks {
+		items, _ := attributes[k].([]interface{})
+		for _, item := range items {
+			body, _ := item.(map[string]interface{})
+			fmt.Fprintf(b, "\n%s%s {\n", indent, k)
+			writeBody(b, body, path+k+".", ignored, depth+1)
+			fmt.Fprintf(b, "%s}\n", indent)
+		}
+	}
+}
+
+// isBlockList reports whether v is a list of objects, which is how state
+// records nested blocks.
+func isBlockList(v interface{}) bool {
+	l, ok := v.([]interface{})
+	if !ok || len(l) == 0 {
+		return false
+	}
+	for _, item := range l {
+		if _, ok := item.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// redacted reports whether v, or any value in it, has been redacted.
+func redacted(v interface{}) bool {
+	switch v := v.(type) {
+	case string:
+		return v == redact.Marker
+	case []interface{}:
+		for _, item := range v {
+			if redacted(item) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			if redacted(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isEmpty(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// value returns v as an HCL expression.
+func value(v interface{}, depth int) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return state.Quote(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = value(item, depth)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		keys := maps.Keys(v)
+		sort.Strings(keys)
+		indent := strings.Repeat("  ", depth)
+		var b strings.Builder
+		b.WriteString("{\n")
+		for _, k := range keys {
+			fmt.Fprintf(&b, "%s  %s = %s\n", indent, state.Quote(k), value(v[k], depth+1))
+		}
+		b.WriteString(indent + "}")
+		return b.String()
+	}
+	return state.Quote(fmt.Sprint(v))
+}
@@ -0,0 +1,104 @@
+package hcl
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cmdpdx/tf-state-import/pkg/redact"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+)
+
+func TestResourceBlock(t *testing.T) {
+	attributes := map[string]interface{}{
+		"id":           "projects/prod/alertPolicies/1",
+		"name":         "projects/prod/alertPolicies/1",
+		"display_name": "API errors",
+		"enabled":      true,
+		"description":  "",
+		"severity":     nil,
+		"user_labels":  map[string]interface{}{"team": "api", "k8s.io/name": "api"},
+		"notification_channels": []interface{}{
+			"projects/prod/notificationChannels/1",
+		},
+		"documentation": []interface{}{},
+		"conditions": []interface{}{
+			map[string]interface{}{
+				"name":         "projects/prod/alertPolicies/1/conditions/1",
+				"display_name": "rate > ${threshold}",
+				"condition_threshold": []interface{}{
+					map[string]interface{}{
+						"duration":        "60s",
+						"threshold_value": float64(0.5),
+					},
+				},
+			},
+		},
+	}
+	want := `resource "google_monitoring_alert_policy" "errors" {
+  display_name = "API errors"
+  enabled = true
+  notification_channels = ["projects/prod/notificationChannels/1"]
+  user_labels = {
+    "k8s.io/name" = "api"
+    "team" = "api"
+  }
+
+  conditions {
+    display_name = "rate > $${threshold}"
+
+    condition_threshold {
+      duration = "60s"
+      threshold_value = 0.5
+    }
+  }
+}
+`
+	got := ResourceBlock("google_monitoring_alert_policy", "errors", attributes)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("ResourceBlock() output mismatch (-want, +got):", diff)
+	}
+}
+
+func TestWriteResources(t *testing.T) {
+	ordered := []*resources.Tuple{
+		{
+			Type:       "t",
+			Name:       "a",
+			Attributes: map[string]interface{}{"id": "a", "size": float64(1000000), "admin_password": "p", "env": map[string]interface{}{"KEY": "k", "NAME": "n"}},
+			Sensitive:  []string{"env.KEY"},
+		},
+		{Type: "t", Name: "b", IndexKey: "k", Attributes: map[string]interface{}{"id": "b"}},
+		{Module: "module.m", Type: "t", Name: "c", Attributes: map[string]interface{}{"id": "c"}},
+		{Module: "module.old", Type: "t", Name: "d", Attributes: map[string]interface{}{"id": "d", "size": json.Number("9007199254740993")}},
+	}
+	rewrite := func(a string) string { return strings.TrimPrefix(a, "module.old.") }
+
+	var b strings.Builder
+	n, err := WriteResources(&b, ordered, rewrite, redact.Default)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("WriteResources() = %d, want 2", n)
+	}
+	want := `resource "t" "a" {
+  admin_password = null # sensitive
+  env = null # sensitive
+  size = 1000000
+}
+
+resource "t" "d" {
+  size = 9007199254740993
+}
+
+# No configuration was generated for these resources in modules or collections:
+#   t.b["k"]
+#   module.m.t.c
+`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Error("WriteResources() output mismatch (-want, +got):", diff)
+	}
+}
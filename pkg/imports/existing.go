@@ -0,0 +1,207 @@
+package imports
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cmdpdx/tf-state-import/pkg/hcl"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+)
+
+// Duplicate is a resource that an import block in the configuration already
+// imports.
+type Duplicate struct {
+	To       string
+	Existing hcl.ImportBlock
+}
+
+func (d Duplicate) String() string {
+	if d.Existing.ForEach || d.Existing.IDExpression != "" {
+		return fmt.Sprintf("%s: already imported by the block at %s, its ID isn't compared", d.To, d.Existing.Pos)
+	}
+	return fmt.Sprintf("%s: already imported by the block at %s", d.To, d.Existing.Pos)
+}
+
+// Conflict is a resource that an import block in the configuration imports
+// with a different ID than the one in state.
+type Conflict struct {
+	To       string
+	ID       string
+	Existing hcl.ImportBlock
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s: the block at %s imports ID %q, but the ID in state is %q", c.To, c.Existing.Pos, c.Existing.ID, c.ID)
+}
+
+// SkipExisting drops the blocks, and the instances of `for_each` blocks, that
+// import a resource which one of the existing import blocks already imports.
+// Existing blocks are matched on their `to` address; a `for_each` block
+// matches every instance of its collection. Resources whose existing block has
+// a different, literal ID are returned as conflicts, and dropped too.
+func SkipExisting(blocks []Block, existing []hcl.ImportBlock) ([]Block, []Duplicate, []Conflict) {
+	single := map[string]hcl.ImportBlock{}
+	collections := map[string]hcl.ImportBlock{}
+	for _, e := range existing {
+		if e.ForEach {
+			if i := strings.LastIndexByte(e.To, '['); i > 0 {
+				collections[e.To[:i]] = e
+			}
+			continue
+		}
+		single[e.To] = e
+	}
+
+	var (
+		kept       []Block
+		duplicates []Duplicate
+		conflicts  []Conflict
+	)
+	// check reports whether the resource at to, with the id, is already
+	// imported.
+	check := func(to, id string) bool {
+		if e, ok := single[to]; ok {
+			if e.IDExpression == "" && e.ID != id {
+				conflicts = append(conflicts, Conflict{To: to, ID: id, Existing: e})
+			} else {
+				duplicates = append(duplicates, Duplicate{To: to, Existing: e})
+			}
+			return true
+		}
+		if e, ok := collections[collectionOf(to)]; ok {
+			duplicates = append(duplicates, Duplicate{To: to, Existing: e})
+			return true
+		}
+		return false
+	}
+
+	for _, b := range blocks {
+		if b.ForEach == nil {
+			if !check(b.To, b.ID) {
+				kept = append(kept, b)
+			}
+			continue
+		}
+		if e, ok := collections[b.To]; ok {
+			duplicates = append(duplicates, Duplicate{To: b.To, Existing: e})
+			continue
+		}
+		remaining := map[string]string{}
+		for _, k := range resources.SortedKeys(b.ForEach) {
+			if !check(resources.InstanceAddress(b.To, k), b.ForEach[k]) {
+				remaining[k] = b.ForEach[k]
+			}
+		}
+		if len(remaining) > 0 {
+			kept = append(kept, Block{To: b.To, ForEach: remaining})
+		}
+	}
+	return kept, duplicates, conflicts
+}
+
+// collectionOf returns the address without its final index key, or "" if it
+// has none.
+func collectionOf(address string) string {
+	if !strings.HasSuffix(address, "]") {
+		return ""
+	}
+	for i := len(address) - 1; i > 0; i-- {
+		if address[i] == '[' && closingBracket(address, i) == len(address)-1 {
+			return address[:i]
+		}
+	}
+	return ""
+}
+
+// Imported reports whether the import block has been applied: the resource it
+// imports, or any instance of the collection for `for_each` blocks, is in
+// state.
+func Imported(b hcl.ImportBlock, state resources.ResourceMap) bool {
+	if !b.ForEach {
+		_, ok := state[b.To]
+		return ok
+	}
+	i := strings.LastIndexByte(b.To, '[')
+	if i <= 0 {
+		return false
+	}
+	for a := range state {
+		if collectionOf(a) == b.To[:i] {
+			return true
+		}
+	}
+	return false
+}
+
+// Removed reports whether the removed block has been applied: no instance of
+// the resource or module it removes is left in state.
+func Removed(b hcl.RemovedBlock, state resources.ResourceMap) bool {
+	for a := range state {
+		if a == b.From || strings.HasPrefix(a, b.From+".") || strings.HasPrefix(a, b.From+"[") {
+			return false
+		}
+	}
+	return true
+}
+
+// GeneratedHeader starts the header of the files that tf-state-import writes.
+const GeneratedHeader = "Generated by tf-state-import"
+
+// Generated reports whether src is a file that tf-state-import wrote, by the
+// header on its first line.
+func Generated(src []byte) bool {
+	return strings.HasPrefix(string(src), "# "+GeneratedHeader)
+}
+
+// Cleanup is an import or removed block that cleanup removes or keeps.
+type Cleanup struct {
+	// Kind is "import" or "removed", and Address the address in its `to` or
+	// `from`.
+	Kind    string
+	Address string
+	Pos     hcl.Pos
+	Range   hcl.Range
+	// Remove is set for blocks that are removed, Reason explains why the
+	// others are kept.
+	Remove bool
+	Reason string
+}
+
+// CleanupBlocks returns the import and removed blocks of root in their order,
+// and whether each is removed: blocks in files that tf-state-import generated
+// are removed once they've been applied to state. Blocks in any other file
+// were written by hand and are always kept.
+func CleanupBlocks(root *hcl.Module, state resources.ResourceMap) ([]Cleanup, error) {
+	var blocks []Cleanup
+	for _, b := range root.Imports {
+		c := Cleanup{Kind: "import", Address: b.To, Pos: b.Pos, Range: b.Range, Remove: Imported(b, state)}
+		if !c.Remove {
+			c.Reason = "it isn't in state yet"
+		}
+		blocks = append(blocks, c)
+	}
+	for _, b := range root.Removed {
+		c := Cleanup{Kind: "removed", Address: b.From, Pos: b.Pos, Range: b.Range, Remove: Removed(b, state)}
+		if !c.Remove {
+			c.Reason = "it's still in state"
+		}
+		blocks = append(blocks, c)
+	}
+	generated := map[string]bool{}
+	for i, c := range blocks {
+		g, ok := generated[c.Pos.File]
+		if !ok {
+			src, err := os.ReadFile(c.Pos.File)
+			if err != nil {
+				return nil, err
+			}
+			g = Generated(src)
+			generated[c.Pos.File] = g
+		}
+		if !g {
+			blocks[i].Remove, blocks[i].Reason = false, "it wasn't generated by tf-state-import"
+		}
+	}
+	return blocks, nil
+}
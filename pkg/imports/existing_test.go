@@ -0,0 +1,135 @@
+package imports
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cmdpdx/tf-state-import/pkg/hcl"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+)
+
+func TestSkipExisting(t *testing.T) {
+	existing := []hcl.ImportBlock{
+		{To: "t.same", ID: "same", Pos: hcl.Pos{File: "main.tf", Line: 1}},
+		{To: "t.other", ID: "old", Pos: hcl.Pos{File: "main.tf", Line: 5}},
+		{To: "t.expr", IDExpression: "var.id", Pos: hcl.Pos{File: "main.tf", Line: 9}},
+		{To: `t.keyed["a"]`, ID: "a", Pos: hcl.Pos{File: "main.tf", Line: 13}},
+		{To: "module.m.t.all[each.key]", IDExpression: "each.value", ForEach: true, Pos: hcl.Pos{File: "main.tf", Line: 17}},
+	}
+	blocks := []Block{
+		{To: "t.same", ID: "same"},
+		{To: "t.other", ID: "new"},
+		{To: "t.expr", ID: "x"},
+		{To: "t.new", ID: "new"},
+		{To: "t.keyed", ForEach: map[string]string{"a": "a", "b": "b"}},
+		{To: `module.m.t.all["x"]`, ID: "x"},
+		{To: "module.m.t.all", ForEach: map[string]string{"y": "y"}},
+	}
+
+	kept, duplicates, conflicts := SkipExisting(blocks, existing)
+
+	wantKept := []Block{
+		{To: "t.new", ID: "new"},
+		{To: "t.keyed", ForEach: map[string]string{"b": "b"}},
+	}
+	if diff := cmp.Diff(wantKept, kept); diff != "" {
+		t.Error("SkipExisting() blocks mismatch (-want, +got):", diff)
+	}
+	wantDuplicates := []Duplicate{
+		{To: "t.same", Existing: existing[0]},
+		{To: "t.expr", Existing: existing[2]},
+		{To: `t.keyed["a"]`, Existing: existing[3]},
+		{To: `module.m.t.all["x"]`, Existing: existing[4]},
+		{To: "module.m.t.all", Existing: existing[4]},
+	}
+	if diff := cmp.Diff(wantDuplicates, duplicates); diff != "" {
+		t.Error("SkipExisting() duplicates mismatch (-want, +got):", diff)
+	}
+	wantConflicts := []Conflict{{To: "t.other", ID: "new", Existing: existing[1]}}
+	if diff := cmp.Diff(wantConflicts, conflicts); diff != "" {
+		t.Error("SkipExisting() conflicts mismatch (-want, +got):", diff)
+	}
+
+	if got, want := conflicts[0].String(), `t.other: the block at main.tf:5 imports ID "old", but the ID in state is "new"`; got != want {
+		t.Errorf("Conflict.String() = %q, want %q", got, want)
+	}
+}
+
+func TestApplied(t *testing.T) {
+	state := resources.ResourceMap{
+		"t.a":                  {},
+		`module.m.t.b["x"]`:    {},
+		`module.n["k"].t.c[0]`: {},
+	}
+	for _, tt := range []struct {
+		block hcl.ImportBlock
+		want  bool
+	}{
+		{hcl.ImportBlock{To: "t.a"}, true},
+		{hcl.ImportBlock{To: "t.missing"}, false},
+		{hcl.ImportBlock{To: "module.m.t.b[each.key]", ForEach: true}, true},
+		{hcl.ImportBlock{To: "module.m.t.c[each.key]", ForEach: true}, false},
+	} {
+		if got := Imported(tt.block, state); got != tt.want {
+			t.Errorf("Imported(%s) = %v, want %v", tt.block.To, got, tt.want)
+		}
+	}
+	for _, tt := range []struct {
+		from string
+		want bool
+	}{
+		{"t.a", false},
+		{"t.gone", true},
+		{"module.m", false},
+		{"module.n", false},
+		{"module.m.t.b", false},
+		{"module.mm", true},
+	} {
+		if got := Removed(hcl.RemovedBlock{From: tt.from}, state); got != tt.want {
+			t.Errorf("Removed(%s) = %v, want %v", tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestCleanupBlocks(t *testing.T) {
+	dir := t.TempDir()
+	generated := File{Name: RootFile, Blocks: []Block{{To: "t.a", ID: "a"}, {To: "t.pending", ID: "p"}}}
+	if _, err := Write(dir, []File{generated}, GeneratedHeader+" from terraform.tfstate.", false); err != nil {
+		t.Fatal(err)
+	}
+	handWritten := `resource "t" "b" {}
+
+import {
+  to = t.b
+  id = "b"
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte(handWritten), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root, err := hcl.LoadModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocks, err := CleanupBlocks(root, resources.ResourceMap{"t.a": {}, "t.b": {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, b := range blocks {
+		got = append(got, fmt.Sprintf("%s %s %s %t %s", b.Kind, b.Address, filepath.Base(b.Pos.File), b.Remove, b.Reason))
+	}
+	want := []string{
+		fmt.Sprintf("import t.a %s true ", RootFile),
+		fmt.Sprintf("import t.pending %s false it isn't in state yet", RootFile),
+		"import t.b main.tf false it wasn't generated by tf-state-import",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("CleanupBlocks() mismatch (-want, +got):", diff)
+	}
+}
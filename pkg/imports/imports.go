@@ -0,0 +1,227 @@
+package imports
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cmdpdx/tf-state-import/pkg/files"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+)
+
+// RootFile is the file that import blocks are written to, and the file for
+// resources in the root module when splitting by module.
+const RootFile = "imports.tf"
+
+const blockTemplate = `import {
+  to = %s
+  id = %s
+}`
+
+const forEachTemplate = `import {
+  for_each = {
+%s  }
+  to = %s[each.key]
+  id = each.value
+}`
+
+// Block is a Terraform import block. Terraform only accepts import blocks in
+// the root module, so To is always a fully qualified address.
+type Block struct {
+	To string
+	ID string
+	// ForEach maps the keys of a `for_each` collection to the ID of each
+	// instance. If set, the block imports every instance of the collection at
+	// To, which has no index key, and ID is unused.
+	ForEach map[string]string
+}
+
+func (b Block) String() string {
+	if b.ForEach == nil {
+		return fmt.Sprintf(blockTemplate, b.To, state.Quote(b.ID))
+	}
+	var items strings.Builder
+	for _, k := range resources.SortedKeys(b.ForEach) {
+		fmt.Fprintf(&items, "    %s = %s\n", state.Quote(k), state.Quote(b.ForEach[k]))
+	}
+	return fmt.Sprintf(forEachTemplate, items.String(), b.To)
+}
+
+// Blocks returns the import blocks for the resources, in order, importing each
+// resource at the address given by rewrite. With forEach, the instances of each
+// `for_each` collection are imported by a single block in place of its first
+// instance.
+func Blocks(ordered []*resources.Tuple, forEach bool, rewrite func(string) string) []Block {
+	rm := make(resources.ResourceMap, len(ordered))
+	for _, r := range ordered {
+		rm[r.Address()] = *r
+	}
+
+	var blocks []Block
+	collected := map[string]bool{}
+	for _, r := range ordered {
+		if _, isKey := r.IndexKey.(string); !forEach || !isKey {
+			blocks = append(blocks, Block{To: rewrite(r.Address()), ID: r.ImportableID()})
+			continue
+		}
+		collection := r.CollectionAddress()
+		if collected[collection] {
+			continue
+		}
+		collected[collection] = true
+		b := Block{To: rewrite(collection), ForEach: map[string]string{}}
+		for _, inst := range rm.Collection(collection) {
+			key, _ := inst.IndexKey.(string)
+			b.ForEach[key] = inst.ImportableID()
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+// File is a .tf file of import blocks.
+type File struct {
+	Name   string
+	Blocks []Block
+}
+
+// Files groups the blocks into files. Without split every block goes to
+// imports.tf, with split the blocks of resources in a module go to
+// imports_<module>.tf instead. Files are ordered by name, blocks keep their
+// order.
+func Files(blocks []Block, split bool) ([]File, error) {
+	byName := map[string]*File{}
+	modules := map[string]string{}
+	var names []string
+	for _, b := range blocks {
+		name := RootFile
+		if split {
+			if module := ModulePath(b.To); module != "" {
+				name = "imports_" + fileSafe(module) + ".tf"
+				if other, ok := modules[name]; ok && other != module {
+					return nil, fmt.Errorf("modules %s and %s would both be written to %s", other, module, name)
+				}
+				modules[name] = module
+			}
+		}
+		f, ok := byName[name]
+		if !ok {
+			f = &File{Name: name}
+			byName[name] = f
+			names = append(names, name)
+		}
+		f.Blocks = append(f.Blocks, b)
+	}
+
+	sort.Strings(names)
+	files := make([]File, len(names))
+	for i, n := range names {
+		files[i] = *byName[n]
+	}
+	return files, nil
+}
+
+// ModulePath returns the module part of a resource address, e.g.
+// `module.a.module.b["x"]` for `module.a.module.b["x"].t.name`, or "" for
+// resources in the root module.
+func ModulePath(address string) string {
+	end := 0
+	for strings.HasPrefix(address[end:], "module.") {
+		i := end + len("module.")
+		for i < len(address) && address[i] != '.' && address[i] != '[' {
+			i++
+		}
+		if i < len(address) && address[i] == '[' {
+			i = closingBracket(address, i)
+			if i < 0 {
+				return address[:end]
+			}
+			i++
+		}
+		end = i
+		if end < len(address) && address[end] == '.' {
+			end++
+		}
+	}
+	return strings.TrimSuffix(address[:end], ".")
+}
+
+// closingBracket returns the index of the bracket closing the one at start,
+// skipping over quoted keys.
+func closingBracket(s string, start int) int {
+	inQuote := false
+	for i := start + 1; i < len(s); i++ {
+		switch {
+		case inQuote && s[i] == '\\':
+			i++
+		case s[i] == '"':
+			inQuote = !inQuote
+		case !inQuote && s[i] == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// fileSafe turns a module path into part of a file name, e.g. `a.b_x` for
+// `module.a.module.b["x"]`.
+func fileSafe(module string) string {
+	name := strings.TrimPrefix(strings.ReplaceAll(module, "module.", ""), ".")
+	return strings.Trim(unsafeChars.ReplaceAllString(name, "_"), "_.")
+}
+
+// Write writes the files to dir, each starting with the header as a comment,
+// and returns their paths. Unless force is set it refuses to overwrite any
+// existing file, and removes the files it wrote if one exists.
+func Write(dir string, out []File, header string, force bool) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	var written, existing []string
+	for _, f := range out {
+		p := filepath.Join(dir, f.Name)
+		err := files.Write(p, []byte(f.Content(header)), 0o644, force)
+		switch {
+		case errors.Is(err, fs.ErrExist):
+			existing = append(existing, p)
+		case err != nil:
+			removeAll(written)
+			return nil, err
+		default:
+			written = append(written, p)
+		}
+	}
+	if len(existing) > 0 {
+		removeAll(written)
+		return nil, fmt.Errorf("refusing to overwrite %s, use -force to overwrite", strings.Join(existing, ", "))
+	}
+	return written, nil
+}
+
+func removeAll(paths []string) {
+	for _, p := range paths {
+		_ = os.Remove(p)
+	}
+}
+
+// Content returns the contents of the file.
+func (f File) Content(header string) string {
+	var b strings.Builder
+	if header != "" {
+		for _, l := range strings.Split(strings.TrimRight(header, "\n"), "\n") {
+			b.WriteString(strings.TrimRight("# "+l, " ") + "\n")
+		}
+	}
+	for _, block := range f.Blocks {
+		b.WriteString("\n" + block.String() + "\n")
+	}
+	return b.String()
+}
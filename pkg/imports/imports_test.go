@@ -0,0 +1,203 @@
+package imports
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+)
+
+func TestBlockString(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		block Block
+		want  string
+	}{{
+		name:  "single",
+		block: Block{To: `t.x["a"]`, ID: "id-a"},
+		want: `import {
+  to = t.x["a"]
+  id = "id-a"
+}`,
+	}, {
+		name:  "escaped id",
+		block: Block{To: "t.x", ID: `a"b\c ${var.x} %{if}`},
+		want: `import {
+  to = t.x
+  id = "a\"b\\c $${var.x} %%{if}"
+}`,
+	}, {
+		name: "for_each",
+		block: Block{To: "module.m.t.x", ForEach: map[string]string{
+			"b":           "id-b",
+			"a":           "id-a",
+			`"quoted" ${`: "100%{x}",
+		}},
+		want: `import {
+  for_each = {
+    "\"quoted\" $${" = "100%%{x}"
+    "a" = "id-a"
+    "b" = "id-b"
+  }
+  to = module.m.t.x[each.key]
+  id = each.value
+}`,
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, tt.block.String()); diff != "" {
+				t.Error("String() output mismatch (-want, +got):", diff)
+			}
+		})
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	ordered := []*resources.Tuple{
+		{Type: "t", Name: "dep", ID: "dep"},
+		{Type: "t", Name: "x", IndexKey: "b", ID: "id-b"},
+		{Type: "t", Name: "counted", IndexKey: float64(0), ID: "c0"},
+		{Type: "t", Name: "x", IndexKey: "a", ID: "id-a"},
+		{Module: "module.old", Type: "t", Name: "y", IndexKey: "k", ID: "id-k"},
+		{Type: "t", Name: "q", IndexKey: `b"x`, ID: "id-q"},
+	}
+	rewrite := func(a string) string { return strings.Replace(a, "module.old", "module.new", 1) }
+
+	for _, tt := range []struct {
+		name    string
+		forEach bool
+		want    []Block
+	}{{
+		name: "one block per instance",
+		want: []Block{
+			{To: "t.dep", ID: "dep"},
+			{To: `t.x["b"]`, ID: "id-b"},
+			{To: "t.counted[0]", ID: "c0"},
+			{To: `t.x["a"]`, ID: "id-a"},
+			{To: `module.new.t.y["k"]`, ID: "id-k"},
+			{To: `t.q["b\"x"]`, ID: "id-q"},
+		},
+	}, {
+		name:    "for_each",
+		forEach: true,
+		want: []Block{
+			{To: "t.dep", ID: "dep"},
+			{To: "t.x", ForEach: map[string]string{"a": "id-a", "b": "id-b"}},
+			{To: "t.counted[0]", ID: "c0"},
+			{To: "module.new.t.y", ForEach: map[string]string{"k": "id-k"}},
+			{To: "t.q", ForEach: map[string]string{`b"x`: "id-q"}},
+		},
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Blocks(ordered, tt.forEach, rewrite)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error("Blocks() return mismatch (-want, +got):", diff)
+			}
+		})
+	}
+}
+
+func TestModulePath(t *testing.T) {
+	for _, tt := range []struct {
+		address string
+		want    string
+	}{
+		{"t.name", ""},
+		{`t.name["module.x"]`, ""},
+		{"module.a.t.name", "module.a"},
+		{"module.a.module.b.t.name[0]", "module.a.module.b"},
+		{`module.a["x.y"].module.b[1].t.name`, `module.a["x.y"].module.b[1]`},
+		{`module.a["x]"].t.name`, `module.a["x]"]`},
+	} {
+		if got := ModulePath(tt.address); got != tt.want {
+			t.Errorf("ModulePath(%q) = %q, want %q", tt.address, got, tt.want)
+		}
+	}
+}
+
+var blocks = []Block{
+	{To: "t.root", ID: "1"},
+	{To: "module.b.t.x", ID: "2"},
+	{To: `module.a["k"].t.y`, ID: "3"},
+	{To: "module.b.t.z", ID: "4"},
+}
+
+func TestFiles(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		split bool
+		want  []File
+	}{{
+		name: "single file",
+		want: []File{{Name: "imports.tf", Blocks: blocks}},
+	}, {
+		name:  "split by module",
+		split: true,
+		want: []File{
+			{Name: "imports.tf", Blocks: []Block{blocks[0]}},
+			{Name: "imports_a_k.tf", Blocks: []Block{blocks[2]}},
+			{Name: "imports_b.tf", Blocks: []Block{blocks[1], blocks[3]}},
+		},
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Files(blocks, tt.split)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error("Files() return mismatch (-want, +got):", diff)
+			}
+		})
+	}
+
+	if _, err := Files([]Block{{To: `module.a["b"].t.x`}, {To: "module.a_b.t.x"}}, true); err == nil {
+		t.Error("Files() of modules with the same file name succeeded")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "imports")
+	files, err := Files(blocks[:2], true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := Write(dir, files, "Generated.\n\nReview before applying.", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{filepath.Join(dir, "imports.tf"), filepath.Join(dir, "imports_b.tf")}, paths); diff != "" {
+		t.Error("Write() return mismatch (-want, +got):", diff)
+	}
+	got, err := os.ReadFile(paths[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# Generated.
+#
+# Review before applying.
+
+import {
+  to = module.b.t.x
+  id = "2"
+}
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Error("Write() file mismatch (-want, +got):", diff)
+	}
+
+	// Nothing is written if any of the files exists.
+	files = append(files, File{Name: "imports_new.tf"})
+	if _, err := Write(dir, files, "", false); err == nil {
+		t.Error("Write() overwrote existing files")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "imports_new.tf")); err == nil {
+		t.Error("Write() wrote a file although others exist")
+	}
+	if _, err := Write(dir, files, "", true); err != nil {
+		t.Errorf("Write() with force = %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cmdpdx/tf-state-import/pkg/execute"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+)
+
+// Status is the outcome of a step as recorded in the journal.
+type Status string
+
+const (
+	Started   Status = "started"
+	Succeeded Status = "succeeded"
+	Failed    Status = "failed"
+)
+
+// Entry is a single line of the journal.
+type Entry struct {
+	Time    time.Time      `json:"time"`
+	Action  execute.Action `json:"action"`
+	Address string         `json:"address"`
+	ID      string         `json:"id,omitempty"`
+	Status  Status         `json:"status"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// Journal is an append-only file of JSON entries, one per line. Every entry is
+// synced to disk before the step it describes runs or is reported finished.
+type Journal struct {
+	mu sync.Mutex
+	f  *os.File
+
+	now func() time.Time
+}
+
+var _ execute.Recorder = (*Journal)(nil)
+
+// Open opens the journal at filename for appending, creating it if necessary.
+func Open(filename string) (*Journal, error) {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &Journal{f: f, now: time.Now}, nil
+}
+
+// Started records that a step is about to run.
+func (j *Journal) Started(s execute.Step) error {
+	return j.append(Entry{Action: s.Action, Address: s.Address, ID: s.ID, Status: Started})
+}
+
+// Finished records the outcome of a step.
+func (j *Journal) Finished(r execute.Result) error {
+	e := Entry{Action: r.Step.Action, Address: r.Step.Address, ID: r.Step.ID, Status: Succeeded}
+	if r.Err != nil {
+		e.Status = Failed
+		e.Error = r.Err.Error()
+	}
+	return j.append(e)
+}
+
+func (j *Journal) append(e Entry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	e.Time = j.now().UTC()
+	bs, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := j.f.Write(append(bs, '\n')); err != nil {
+		return err
+	}
+	return j.f.Sync()
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	return j.f.Close()
+}
+
+// Read returns the entries of the journal at filename. A torn last line, left
+// behind when the process died mid-write, is ignored.
+func Read(filename string) ([]Entry, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parse(f)
+}
+
+func parse(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		bs, err := br.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// No trailing newline means the write never completed.
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var e Entry
+		if err := json.Unmarshal(bs, &e); err != nil {
+			return nil, fmt.Errorf("journal line %d: %w", line, err)
+		}
+		entries = append(entries, e)
+	}
+}
+
+// Reconcile returns the steps of the plan that still need to run, in plan order,
+// given the journal of a previous run and the resources currently in state.
+//
+// The current state wins over the journal, since the process may have died
+// after a step took effect but before its outcome was recorded:
+//   - a remove is done if the journal says it succeeded or its address is no
+//     longer in state.
+//   - an import is done if its address is in state and its remove, if any,
+//     is done.
+//   - a taint is done if the journal says it succeeded or the resource is
+//     tainted in state.
+//
+// Warnings describe where the journal and the state disagree.
+func Reconcile(plan []execute.Step, entries []Entry, current resources.ResourceMap) ([]execute.Step, []string) {
+	inState := func(address string) bool {
+		_, ok := current[address]
+		return ok
+	}
+
+	last := make(map[execute.Step]Status, len(entries))
+	for _, e := range entries {
+		last[execute.Step{Action: e.Action, Address: e.Address, ID: e.ID}] = e.Status
+	}
+
+	var warnings []string
+	removePending := make(map[string]bool)
+	remaining := make([]execute.Step, 0, len(plan))
+	for _, s := range plan {
+		if s.Action != execute.Remove {
+			continue
+		}
+		// A removed resource is back in state once it has been re-imported.
+		if inState(s.Address) && last[s] != Succeeded {
+			removePending[s.Address] = true
+		}
+	}
+
+	for _, s := range plan {
+		switch {
+		case s.Action == execute.Remove:
+			if removePending[s.Address] {
+				remaining = append(remaining, s)
+			}
+		case s.Action == execute.Taint:
+			if last[s] != Succeeded && !current[s.Address].Tainted {
+				remaining = append(remaining, s)
+			}
+		case inState(s.Address) && !removePending[s.Address]:
+			if last[s] != Succeeded {
+				warnings = append(warnings, fmt.Sprintf("%s: in state but journal has no successful import, assuming it was imported", s))
+			}
+		default:
+			if last[s] == Succeeded {
+				warnings = append(warnings, fmt.Sprintf("%s: journal says imported, but it is not in state", s))
+			}
+			remaining = append(remaining, s)
+		}
+	}
+	return remaining, warnings
+}
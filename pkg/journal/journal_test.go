@@ -0,0 +1,233 @@
+package journal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cmdpdx/tf-state-import/pkg/execute"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+)
+
+func TestJournal(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "journal.json")
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	j, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.now = func() time.Time { return now }
+
+	rm := execute.Step{Action: execute.Remove, Address: "t.foo"}
+	imp := execute.Step{Action: execute.Import, Address: "t.foo", ID: "foo-id"}
+	for _, err := range []error{
+		j.Started(rm),
+		j.Finished(execute.Result{Step: rm}),
+		j.Started(imp),
+		j.Finished(execute.Result{Step: imp, Err: errors.New("boom")}),
+		j.Close(),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Reopening appends rather than truncating.
+	j, err = Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.now = func() time.Time { return now }
+	if err := j.Started(imp); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate dying halfway through writing an entry.
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"time":"2024-01-02T03:04:05Z","act`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	got, err := Read(filename)
+	if err != nil {
+		t.Fatalf("Read() = %v", err)
+	}
+	want := []Entry{
+		{Time: now, Action: execute.Remove, Address: "t.foo", Status: Started},
+		{Time: now, Action: execute.Remove, Address: "t.foo", Status: Succeeded},
+		{Time: now, Action: execute.Import, Address: "t.foo", ID: "foo-id", Status: Started},
+		{Time: now, Action: execute.Import, Address: "t.foo", ID: "foo-id", Status: Failed, Error: "boom"},
+		{Time: now, Action: execute.Import, Address: "t.foo", ID: "foo-id", Status: Started},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("Read() return mismatch (-want, +got):", diff)
+	}
+}
+
+func TestParseCorrupt(t *testing.T) {
+	_, err := parse(strings.NewReader("{\"address\":\"t.foo\"}\nnot json\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("parse() error = %v, want error on line 2", err)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	step := func(a execute.Action, address string) execute.Step {
+		s := execute.Step{Action: a, Address: address}
+		if a == execute.Import {
+			s.ID = address + "-id"
+		}
+		return s
+	}
+	entry := func(a execute.Action, address string, status Status) Entry {
+		s := step(a, address)
+		return Entry{Action: s.Action, Address: s.Address, ID: s.ID, Status: status}
+	}
+	plan := []execute.Step{
+		step(execute.Remove, "t.b"),
+		step(execute.Remove, "t.a"),
+		step(execute.Import, "t.a"),
+		step(execute.Import, "t.b"),
+	}
+	current := func(addresses ...string) resources.ResourceMap {
+		rm := resources.ResourceMap{}
+		for _, a := range addresses {
+			rm[a] = resources.Tuple{}
+		}
+		return rm
+	}
+
+	for _, tt := range []struct {
+		name         string
+		entries      []Entry
+		current      resources.ResourceMap
+		want         []execute.Step
+		wantWarnings int
+	}{{
+		name:    "nothing ran",
+		current: current("t.a", "t.b"),
+		want:    plan,
+	}, {
+		name: "died after one remove",
+		entries: []Entry{
+			entry(execute.Remove, "t.b", Started),
+			entry(execute.Remove, "t.b", Succeeded),
+		},
+		current: current("t.a"),
+		want:    plan[1:],
+	}, {
+		name: "died during a remove that took effect",
+		entries: []Entry{
+			entry(execute.Remove, "t.b", Started),
+		},
+		current: current("t.a"),
+		want:    plan[1:],
+	}, {
+		name: "one import done",
+		entries: []Entry{
+			entry(execute.Remove, "t.b", Succeeded),
+			entry(execute.Remove, "t.a", Succeeded),
+			entry(execute.Import, "t.a", Succeeded),
+		},
+		current: current("t.a"),
+		want:    plan[3:],
+	}, {
+		name: "import took effect but was never recorded",
+		entries: []Entry{
+			entry(execute.Remove, "t.b", Succeeded),
+			entry(execute.Remove, "t.a", Succeeded),
+			entry(execute.Import, "t.a", Started),
+		},
+		current:      current("t.a"),
+		want:         plan[3:],
+		wantWarnings: 1,
+	}, {
+		name: "failed import",
+		entries: []Entry{
+			entry(execute.Remove, "t.b", Succeeded),
+			entry(execute.Remove, "t.a", Succeeded),
+			entry(execute.Import, "t.a", Failed),
+		},
+		current: current(),
+		want:    plan[2:],
+	}, {
+		name: "journal says imported but state disagrees",
+		entries: []Entry{
+			entry(execute.Remove, "t.b", Succeeded),
+			entry(execute.Remove, "t.a", Succeeded),
+			entry(execute.Import, "t.a", Succeeded),
+		},
+		current:      current(),
+		want:         plan[2:],
+		wantWarnings: 1,
+	}, {
+		name: "everything done",
+		entries: []Entry{
+			entry(execute.Remove, "t.b", Succeeded),
+			entry(execute.Remove, "t.a", Succeeded),
+			entry(execute.Import, "t.a", Succeeded),
+			entry(execute.Import, "t.b", Succeeded),
+		},
+		current: current("t.a", "t.b"),
+		want:    []execute.Step{},
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings := Reconcile(plan, tt.entries, tt.current)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error("Reconcile() return mismatch (-want, +got):", diff)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Reconcile() warnings = %q, want %d", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestReconcileTaint(t *testing.T) {
+	imp := execute.Step{Action: execute.Import, Address: "t.a", ID: "a"}
+	taint := execute.Step{Action: execute.Taint, Address: "t.a"}
+	plan := []execute.Step{imp, taint}
+	imported := Entry{Action: execute.Import, Address: "t.a", ID: "a", Status: Succeeded}
+
+	for _, tt := range []struct {
+		name    string
+		entries []Entry
+		current resources.ResourceMap
+		want    []execute.Step
+	}{{
+		name:    "imported, not tainted yet",
+		entries: []Entry{imported},
+		current: resources.ResourceMap{"t.a": {}},
+		want:    []execute.Step{taint},
+	}, {
+		name:    "tainted in state",
+		entries: []Entry{imported, {Action: execute.Taint, Address: "t.a", Status: Started}},
+		current: resources.ResourceMap{"t.a": {Tainted: true}},
+		want:    []execute.Step{},
+	}, {
+		name:    "journal says tainted",
+		entries: []Entry{imported, {Action: execute.Taint, Address: "t.a", Status: Succeeded}},
+		current: resources.ResourceMap{"t.a": {}},
+		want:    []execute.Step{},
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := Reconcile(plan, tt.entries, tt.current)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error("Reconcile() return mismatch (-want, +got):", diff)
+			}
+		})
+	}
+}
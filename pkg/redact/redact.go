@@ -0,0 +1,131 @@
+// Package redact hides sensitive attribute values wherever attributes are
+// shown.
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+)
+
+// Marker replaces every redacted value.
+const Marker = "(sensitive)"
+
+// DefaultPatterns are the attribute names that are redacted even if state
+// doesn't mark them as sensitive.
+var DefaultPatterns = []string{
+	"password",
+	"*_password",
+	"secret",
+	"*_secret",
+	"secret_data",
+	"private_key",
+	"*_private_key",
+	"token",
+	"*_token",
+	"access_key",
+	"secret_key",
+	"credentials",
+}
+
+// Policy decides which attribute values are redacted. Values are addressed by
+// path the way Terraform does, e.g. `rule[0].password`.
+type Policy struct {
+	// Patterns are path.Match patterns of attribute names, or of paths without
+	// index keys such as `settings.password`, whose values are redacted.
+	Patterns []string
+	// Disabled turns redaction off.
+	Disabled bool
+}
+
+// Default is the policy used unless configured otherwise.
+var Default = Policy{Patterns: DefaultPatterns}
+
+var indexKeys = regexp.MustCompile(`\[[^\]]*\]`)
+
+// Sensitive reports whether the value at the path is redacted, given the
+// sensitive paths that state recorded for its resource instance.
+func (p Policy) Sensitive(at string, sensitive []string) bool {
+	if p.Disabled || at == "" {
+		return false
+	}
+	for _, s := range sensitive {
+		if at == s || strings.HasPrefix(at, s+".") || strings.HasPrefix(at, s+"[") {
+			return true
+		}
+	}
+	unindexed := indexKeys.ReplaceAllString(at, "")
+	name := unindexed[strings.LastIndexByte(unindexed, '.')+1:]
+	for _, pattern := range p.Patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, unindexed); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Value returns a copy of v, the value at the path, with every sensitive value
+// in it replaced by Marker. Null values are kept, there is nothing to hide.
+func (p Policy) Value(at string, v interface{}, sensitive []string) interface{} {
+	if v == nil {
+		return nil
+	}
+	if p.Sensitive(at, sensitive) {
+		return Marker
+	}
+	if p.Disabled {
+		return v
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = p.Value(JoinKey(at, k), item, sensitive)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = p.Value(fmt.Sprintf("%s[%d]", at, i), item, sensitive)
+		}
+		return out
+	}
+	return v
+}
+
+// Attributes returns a copy of the attributes of a resource instance with
+// every sensitive value replaced by Marker.
+func (p Policy) Attributes(attributes map[string]interface{}, sensitive []string) map[string]interface{} {
+	if attributes == nil {
+		return nil
+	}
+	redacted, _ := p.Value("", attributes, sensitive).(map[string]interface{})
+	return redacted
+}
+
+// Format returns a value the way attributes are shown, as JSON.
+func Format(v interface{}) string {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(bs)
+}
+
+// JoinKey appends a map key to a path, quoting keys that aren't identifiers.
+func JoinKey(at, key string) string {
+	if !state.IsIdentifier(key) {
+		return fmt.Sprintf("%s[%q]", at, key)
+	}
+	if at == "" {
+		return key
+	}
+	return at + "." + key
+}
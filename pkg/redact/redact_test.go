@@ -0,0 +1,95 @@
+package redact
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSensitive(t *testing.T) {
+	sensitive := []string{"rule[0].secret_value", "env"}
+	for _, tt := range []struct {
+		path string
+		want bool
+	}{
+		{"rule[0].secret_value", true},
+		{"rule[1].secret_value", false},
+		{"env.DB", true},
+		{`env["a b"]`, true},
+		{"environment", false},
+		{"password", true},
+		{"admin_password", true},
+		{"settings[0].auth_token", true},
+		{"password_length", false},
+		{"name", false},
+		{"", false},
+	} {
+		if got := Default.Sensitive(tt.path, sensitive); got != tt.want {
+			t.Errorf("Sensitive(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	p := Policy{Patterns: []string{"settings.key"}}
+	if !p.Sensitive("settings[0].key", nil) || p.Sensitive("key", nil) {
+		t.Error("Sensitive() doesn't match patterns of paths")
+	}
+	if (Policy{Patterns: DefaultPatterns, Disabled: true}).Sensitive("password", sensitive) {
+		t.Error("Sensitive() redacts with a disabled policy")
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	attributes := map[string]interface{}{
+		"name":     "db",
+		"password": "hunter2",
+		"token":    nil,
+		"rule": []interface{}{
+			map[string]interface{}{"name": "a", "value": "1"},
+			map[string]interface{}{"name": "b", "value": "2"},
+		},
+		"labels": map[string]interface{}{"env": "prod"},
+	}
+	sensitive := []string{"rule[1].value", "labels"}
+
+	got := Default.Attributes(attributes, sensitive)
+	want := map[string]interface{}{
+		"name":     "db",
+		"password": Marker,
+		"token":    nil,
+		"rule": []interface{}{
+			map[string]interface{}{"name": "a", "value": "1"},
+			map[string]interface{}{"name": "b", "value": Marker},
+		},
+		"labels": Marker,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("Attributes() return mismatch (-want, +got):", diff)
+	}
+	if attributes["password"] != "hunter2" {
+		t.Error("Attributes() changed its argument")
+	}
+
+	disabled := Policy{Patterns: DefaultPatterns, Disabled: true}
+	if diff := cmp.Diff(attributes, disabled.Attributes(attributes, sensitive)); diff != "" {
+		t.Error("Attributes() with a disabled policy mismatch (-want, +got):", diff)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	for _, tt := range []struct {
+		v    interface{}
+		want string
+	}{
+		{"s", `"s"`},
+		{nil, "null"},
+		{float64(1.5), "1.5"},
+		{json.Number("9007199254740993"), "9007199254740993"},
+		{[]interface{}{"a", true}, `["a",true]`},
+		{Marker, `"(sensitive)"`},
+	} {
+		if got := Format(tt.v); got != tt.want {
+			t.Errorf("Format(%#v) = %s, want %s", tt.v, got, tt.want)
+		}
+	}
+}
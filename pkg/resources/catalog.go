@@ -0,0 +1,101 @@
+package resources
+
+import (
+	"path"
+	"sort"
+)
+
+// NonImportable describes resource types that can't be imported, or that lose
+// values, such as generated secrets or triggers, when they're re-imported.
+type NonImportable struct {
+	// Types is the pattern of the resource types, as matched by path.Match.
+	Types string
+	// Reason is why the resources can't be migrated with rm and import.
+	Reason string
+	// Preserve is how to migrate the resources without losing their values.
+	Preserve string
+}
+
+// catalog is checked in order, the first entry that matches a type applies.
+var catalog = []NonImportable{{
+	Types:    "random_id",
+	Reason:   "is imported from its b64_url value, without its keepers, prefix or byte_length",
+	Preserve: "keep it in state, or import it by its b64_url and set the same byte_length and prefix in configuration",
+}, {
+	Types:    "random_password",
+	Reason:   "is imported from its result, with length and character settings reset to their defaults",
+	Preserve: "keep it in state, or import it by its result and set the same length, special, upper, lower and numeric in configuration",
+}, {
+	Types:    "random_string",
+	Reason:   "is imported from its result, with length and character settings reset to their defaults",
+	Preserve: "keep it in state, or import it by its result and set the same length, special, upper, lower and numeric in configuration",
+}, {
+	Types:    "random_*",
+	Reason:   "holds a generated value that isn't restored by importing",
+	Preserve: "keep it in state, or replace it with its current value in configuration",
+}, {
+	Types:    "null_resource",
+	Reason:   "can't be imported",
+	Preserve: "keep it in state, or let Terraform create it again, which runs its provisioners again",
+}, {
+	Types:    "terraform_data",
+	Reason:   "can't be imported",
+	Preserve: "keep it in state, or let Terraform create it again, which replaces its output and runs its provisioners again",
+}, {
+	Types:    "tls_*",
+	Reason:   "can't be imported, a new key or certificate would be generated",
+	Preserve: "keep it in state, or store the key or certificate in a secret manager and read it from there",
+}, {
+	Types:    "time_sleep",
+	Reason:   "can't be imported",
+	Preserve: "keep it in state, or let Terraform create it again, which waits again",
+}, {
+	Types:    "time_*",
+	Reason:   "is imported from its timestamps, without its triggers",
+	Preserve: "keep it in state, or import it and set the same triggers in configuration",
+}, {
+	Types:    "local_*",
+	Reason:   "can't be imported",
+	Preserve: "keep it in state, or let Terraform write the file again",
+}}
+
+// Catalog returns the resource types that aren't migrated with rm and import
+// by default, in the order they're matched.
+func Catalog() []NonImportable {
+	return append([]NonImportable{}, catalog...)
+}
+
+// NonImportable returns the catalog entry for the type of the resource, if it
+// can't be imported without losing values.
+func (r Tuple) NonImportable() (NonImportable, bool) {
+	for _, n := range catalog {
+		if ok, _ := path.Match(n.Types, r.Type); ok {
+			return n, true
+		}
+	}
+	return NonImportable{}, false
+}
+
+// Excluded is a resource that the catalog leaves out of the migration.
+type Excluded struct {
+	Address string
+	NonImportable
+}
+
+// SplitNonImportable returns the resources of the map that can be imported,
+// and those of a type in the catalog ordered by address.
+func (rm ResourceMap) SplitNonImportable() (ResourceMap, []Excluded) {
+	importable := make(ResourceMap, len(rm))
+	var excluded []Excluded
+	for a, r := range rm {
+		if n, ok := r.NonImportable(); ok {
+			excluded = append(excluded, Excluded{Address: a, NonImportable: n})
+			continue
+		}
+		importable[a] = r
+	}
+	sort.Slice(excluded, func(i, j int) bool {
+		return excluded[i].Address < excluded[j].Address
+	})
+	return importable, excluded
+}
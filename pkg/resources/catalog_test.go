@@ -0,0 +1,53 @@
+package resources
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTupleNonImportable(t *testing.T) {
+	for _, tt := range []struct {
+		typ       string
+		wantTypes string
+	}{
+		{typ: "random_password", wantTypes: "random_password"},
+		{typ: "random_pet", wantTypes: "random_*"},
+		{typ: "null_resource", wantTypes: "null_resource"},
+		{typ: "terraform_data", wantTypes: "terraform_data"},
+		{typ: "tls_private_key", wantTypes: "tls_*"},
+		{typ: "time_sleep", wantTypes: "time_sleep"},
+		{typ: "time_rotating", wantTypes: "time_*"},
+		{typ: "local_file", wantTypes: "local_*"},
+		{typ: "google_storage_bucket"},
+		{typ: "randomizer_thing"},
+	} {
+		t.Run(tt.typ, func(t *testing.T) {
+			n, ok := Tuple{Type: tt.typ}.NonImportable()
+			if ok != (tt.wantTypes != "") || n.Types != tt.wantTypes {
+				t.Errorf("NonImportable() = %q, %v, want %q", n.Types, ok, tt.wantTypes)
+			}
+		})
+	}
+}
+
+func TestSplitNonImportable(t *testing.T) {
+	rm := ResourceMap{
+		"random_password.db": {Type: "random_password", Name: "db"},
+		"null_resource.hook": {Type: "null_resource", Name: "hook"},
+		"google_sql_user.db": {Type: "google_sql_user", Name: "db"},
+	}
+	importable, excluded := rm.SplitNonImportable()
+
+	if diff := cmp.Diff(ResourceMap{"google_sql_user.db": rm["google_sql_user.db"]}, importable); diff != "" {
+		t.Error("SplitNonImportable() importable mismatch (-want, +got):", diff)
+	}
+	var got []string
+	for _, e := range excluded {
+		got = append(got, e.Address+" "+e.Types)
+	}
+	want := []string{"null_resource.hook null_resource", "random_password.db random_password"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("SplitNonImportable() excluded mismatch (-want, +got):", diff)
+	}
+}
@@ -1,6 +1,7 @@
 package resources
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"regexp"
@@ -23,11 +24,30 @@ type Tuple struct {
 	IndexKey     interface{}
 	Dependencies []string
 	Attributes   map[string]interface{}
+	// Sensitive are the paths of the attribute values that state marks as
+	// sensitive, e.g. `rule[0].password`.
+	Sensitive []string
+	// Tainted is set for instances that are marked for replacement.
+	Tainted bool
+}
+
+// Skipped is a resource instance that can't be imported.
+type Skipped struct {
+	Address string
+	Reason  string
 }
 
 // FromState returns a map of resource name to ResourceTuple from the given state struct.
 func FromState(state state.V4, provider string) ResourceMap {
+	rm, _ := Collect(state, provider)
+	return rm
+}
+
+// Collect is like FromState, but also returns the managed resource instances
+// that match the provider and were left out because they can't be imported.
+func Collect(state state.V4, provider string) (ResourceMap, []Skipped) {
 	rm := make(map[string]Tuple, len(state.Resources))
+	var skipped []Skipped
 	for _, r := range state.Resources {
 		if r.Mode == "data" {
 			continue
@@ -36,30 +56,91 @@ func FromState(state state.V4, provider string) ResourceMap {
 			continue
 		}
 		for _, inst := range r.Instances {
-			rawID, ok := inst.Attributes["id"]
-			if !ok {
-				// Resource doesn't have ID attribute
-				continue
-			}
-			id, ok := rawID.(string)
-			if !ok {
-				// Resource ID wasn't a string
+			// Deposed objects share the address of the current object, they
+			// can't be imported, see Deposed.
+			if inst.Deposed != "" {
 				continue
 			}
 			t := Tuple{
 				Module:       r.Module,
 				Type:         r.Type,
 				Name:         r.Name,
-				ID:           id,
 				IndexKey:     inst.IndexKey,
 				Dependencies: inst.Dependencies,
-				Attributes:   inst.Attributes,
+				Attributes:   inst.ExactAttributes(),
+				Tainted:      inst.Tainted(),
+			}
+			for _, p := range inst.SensitiveAttributes {
+				t.Sensitive = append(t.Sensitive, p.String())
+			}
+			switch id := t.Attributes["id"].(type) {
+			case string:
+				t.ID = id
+			case json.Number, float64:
+				t.ID = FormatValue(id)
+			case nil:
+				// Resources without an id, such as some of the plugin
+				// framework, can still have a rule that builds their
+				// import ID from other attributes.
+			default:
+				skipped = append(skipped, Skipped{Address: t.Address(), Reason: fmt.Sprintf("resource id %v isn't a string or a number", id)})
+				continue
+			}
+			if _, rule := t.ImportRule(); t.ID == "" && len(t.MissingAttributes(rule)) > 0 {
+				skipped = append(skipped, Skipped{Address: t.Address(), Reason: missingReason(t, rule)})
+				continue
 			}
 			rm[t.Address()] = t
 		}
 	}
 
-	return rm
+	return rm, skipped
+}
+
+// DeposedObject is an object that Terraform failed to destroy after replacing
+// it with create_before_destroy. Removing its resource from state orphans it,
+// so it has to be destroyed outside of Terraform.
+type DeposedObject struct {
+	Address string
+	Key     string
+	ID      string
+}
+
+func (d DeposedObject) String() string {
+	return fmt.Sprintf("%s (deposed %s, id %s)", d.Address, d.Key, d.ID)
+}
+
+// Deposed returns the deposed objects of the managed resources that match the
+// provider, ordered by address and key.
+func Deposed(state state.V4, provider string) []DeposedObject {
+	var deposed []DeposedObject
+	for _, r := range state.Resources {
+		if r.Mode == "data" || provider != "" && !strings.Contains(r.Provider, provider) {
+			continue
+		}
+		for _, inst := range r.Instances {
+			if inst.Deposed == "" {
+				continue
+			}
+			t := Tuple{Module: r.Module, Type: r.Type, Name: r.Name, IndexKey: inst.IndexKey}
+			id := FormatValue(inst.ExactAttributes()["id"])
+			deposed = append(deposed, DeposedObject{Address: t.Address(), Key: inst.Deposed, ID: id})
+		}
+	}
+	sort.Slice(deposed, func(i, j int) bool {
+		if deposed[i].Address != deposed[j].Address {
+			return deposed[i].Address < deposed[j].Address
+		}
+		return deposed[i].Key < deposed[j].Key
+	})
+	return deposed
+}
+
+func missingReason(t Tuple, rule Rule) string {
+	if rule.Name == "default" {
+		return "resource doesn't have an id attribute, and no import rule for its type builds one from other attributes"
+	}
+	return fmt.Sprintf("import rule %s needs the missing attributes %s", rule.Name, strings.Join(t.MissingAttributes(rule), ", "))
 }
 
 // Address is the unique friendly name of a resource as [{Module}.]{Type}.{Name}.
@@ -67,45 +148,45 @@ func FromState(state state.V4, provider string) ResourceMap {
 // resources defined with `for_each` have an index key and are
 // addressed as {Type}.{Name}["{Index key}"]
 func (r Tuple) Address() string {
-	a := fmt.Sprintf("%s.%s", r.Type, r.Name)
-	if r.Module != "" {
-		a = fmt.Sprintf("%s.%s", r.Module, a)
-	}
+	return InstanceAddress(r.CollectionAddress(), r.IndexKey)
+}
 
-	switch v := r.IndexKey.(type) {
+// InstanceAddress returns the address of the instance of the collection at
+// address with the index key. String keys are quoted the way Terraform does.
+func InstanceAddress(address string, key interface{}) string {
+	switch v := key.(type) {
 	case int, float64:
-		return fmt.Sprintf("%s[%v]", a, v)
+		return fmt.Sprintf("%s[%v]", address, v)
 	case string:
-		return fmt.Sprintf("%s[\"%s\"]", a, v)
+		return fmt.Sprintf("%s[%s]", address, state.Quote(v))
 	default:
-		return a
+		return address
+	}
+}
+
+// CollectionAddress is the address of the resource without its index key, as
+// [{Module}.]{Type}.{Name}. All instances of a collection share it.
+func (r Tuple) CollectionAddress() string {
+	a := fmt.Sprintf("%s.%s", r.Type, r.Name)
+	if r.Module != "" {
+		a = fmt.Sprintf("%s.%s", r.Module, a)
 	}
+	return a
 }
 
 // ImportableID returns the id as expected by terraform to import the resource.
 // For most resources, this is just the id as listed in the state file.
 // However, there are some special cases that can be handled here.
 func (r Tuple) ImportableID() string {
-	switch {
-	case r.Type == "google_project_iam_member":
-		// TODO: condition
-		return fmt.Sprintf("%s %s %s", r.Attributes["project"], r.Attributes["role"], r.Attributes["member"])
-	case r.Type == "google_secret_manager_secret_iam_member":
-		return fmt.Sprintf("%s %s %s", r.Attributes["secret_id"], r.Attributes["role"], r.Attributes["member"])
-	case strings.HasSuffix(r.Type, "_iam_member"):
-		return fmt.Sprintf("%s %s %s", r.Attributes["name"], r.Attributes["role"], r.Attributes["member"])
-	case r.Type == "google_storage_bucket_iam_binding":
-		// TODO: condition
-		//nolint:forcetypeassert // we know bucket is a string
-		return fmt.Sprintf("%s %s", strings.TrimPrefix(r.Attributes["bucket"].(string), "b/"), r.Attributes["role"])
-	default:
-		return r.ID
-	}
+	id, _ := r.ImportRule()
+	return id
 }
 
 type resourceOrdering struct {
 	m       map[string]Tuple
 	ordered []*Tuple
+	// err is the first cycle found while ordering.
+	err error
 
 	checking map[string]interface{}
 	done     map[string]interface{}
@@ -115,12 +196,53 @@ type resourceOrdering struct {
 }
 
 // Order returns a slice of Tuples in order of least to most dependent
-// resource.
-func (rm *ResourceMap) Order() []*Tuple {
+// resource. It returns an error if the dependencies contain a cycle.
+func (rm *ResourceMap) Order() ([]*Tuple, error) {
 	ro := resourceOrdering{
 		m: *rm,
 	}
-	return ro.order()
+	ordered := ro.order()
+	return ordered, ro.err
+}
+
+// DependencyAddresses returns the address of every resource in the map that
+// each resource depends on, with collection dependencies expanded to each of
+// their instances. Dependencies on resources outside the map are dropped.
+func (rm *ResourceMap) DependencyAddresses() map[string][]string {
+	ro := resourceOrdering{
+		m: *rm,
+	}
+	deps := make(map[string][]string, len(*rm))
+	for _, key := range ro.getKeys() {
+		for _, dep := range ro.dependencies(ro.m[key]) {
+			deps[key] = append(deps[key], dep.Address())
+		}
+	}
+	return deps
+}
+
+// ResolveDependency returns the addresses of the resources in the map that a
+// dependency, as recorded in state, refers to. Dependencies on collections
+// resolve to every instance of the collection, dependencies on data sources
+// and on resources outside the map resolve to nothing.
+func (rm *ResourceMap) ResolveDependency(dep string) []string {
+	ro := resourceOrdering{
+		m: *rm,
+	}
+	var addresses []string
+	for _, r := range ro.dependencies(Tuple{Dependencies: []string{dep}}) {
+		addresses = append(addresses, r.Address())
+	}
+	return addresses
+}
+
+// Collection returns the instances of the `for_each` collection at address,
+// the address of the resource without an index key, ordered by address.
+func (rm *ResourceMap) Collection(address string) []Tuple {
+	ro := resourceOrdering{
+		m: *rm,
+	}
+	return ro.collectionResources(address)
 }
 
 // order walks the dependencies of resources in a depth-first search to produce an ordered
@@ -140,17 +262,36 @@ func (ro *resourceOrdering) order() []*Tuple {
 
 func (ro *resourceOrdering) getKeys() []string {
 	ro.once.Do(func() {
-		ro.keys = maps.Keys(ro.m)
-		sort.Strings(ro.keys)
+		ro.keys = SortedKeys(ro.m)
 	})
 	return ro.keys
 }
 
+// SortedKeys returns the keys of m in order.
+func SortedKeys[V any](m map[string]V) []string {
+	keys := maps.Keys(m)
+	sort.Strings(keys)
+	return keys
+}
+
+// collectionResources returns the instances of the `for_each` collection at
+// address.
 func (ro *resourceOrdering) collectionResources(address string) []Tuple {
+	return ro.matching(fmt.Sprintf(`^%s\[".+"\]$`, regexp.QuoteMeta(address)))
+}
+
+// instances returns the instances of the `count` or `for_each` collection at
+// address.
+func (ro *resourceOrdering) instances(address string) []Tuple {
+	return ro.matching(fmt.Sprintf(`^%s\[(\d+|".+")\]$`, regexp.QuoteMeta(address)))
+}
+
+// matching returns the resources whose address matches the expression.
+func (ro *resourceOrdering) matching(expr string) []Tuple {
 	rs := make([]Tuple, 0, 4)
-	re, err := regexp.Compile(fmt.Sprintf(`%s\[".+"\]`, address))
+	re, err := regexp.Compile(expr)
 	if err != nil {
-		log.Println("failed to compile regex, can't find collection resources:", address)
+		log.Println("failed to compile regex, can't find collection resources:", expr)
 		return nil
 	}
 
@@ -162,35 +303,43 @@ func (ro *resourceOrdering) collectionResources(address string) []Tuple {
 	return rs
 }
 
-func (ro *resourceOrdering) visit(r Tuple) {
-	if _, found := ro.done[r.Address()]; found {
-		return
-	}
-	if _, found := ro.checking[r.Address()]; found {
-		log.Fatalf("cycle detected at: %#v", r)
-	}
-
-	ro.checking[r.Address()] = struct{}{}
-
+// dependencies returns the resources in the map that r depends on.
+func (ro *resourceOrdering) dependencies(r Tuple) []Tuple {
+	var deps []Tuple
 	for _, d := range r.Dependencies {
 		// Skip data dependencies.
 		if strings.HasPrefix(d, "data.") {
 			continue
 		}
 		// Collections dependencies do not include their index key
-		// Look for all resources matching `{d}\[".+"\]`
+		// Look for all resources matching `{d}\[".+"\]` or `{d}\[\d+\]`
 		// e.g if the dependency is my_resource.name, look for all resources
-		// that match my_resource.name["key"]
-		if _, ok := ro.m[d]; !ok {
-			deps := ro.collectionResources(d)
-
-			for _, dep := range deps {
-				ro.visit(dep)
-			}
+		// that match my_resource.name["key"] or my_resource.name[0]
+		if dep, ok := ro.m[d]; ok {
+			deps = append(deps, dep)
 		} else {
-			ro.visit(ro.m[d])
+			deps = append(deps, ro.instances(d)...)
 		}
 	}
+	return deps
+}
+
+func (ro *resourceOrdering) visit(r Tuple) {
+	if _, found := ro.done[r.Address()]; found {
+		return
+	}
+	if _, found := ro.checking[r.Address()]; found {
+		if ro.err == nil {
+			ro.err = fmt.Errorf("cycle detected at: %s", r.Address())
+		}
+		return
+	}
+
+	ro.checking[r.Address()] = struct{}{}
+
+	for _, dep := range ro.dependencies(r) {
+		ro.visit(dep)
+	}
 
 	delete(ro.checking, r.Address())
 	ro.done[r.Address()] = struct{}{}
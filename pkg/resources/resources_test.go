@@ -44,6 +44,14 @@ func TestTupleAddress(t *testing.T) {
 			IndexKey: "key",
 		},
 		want: "type.name[\"key\"]",
+	}, {
+		name: "type, name, and string index with quotes",
+		t: Tuple{
+			Type:     "type",
+			Name:     "name",
+			IndexKey: `b"x`,
+		},
+		want: `type.name["b\"x"]`,
 	}, {
 		name: "module, type, and name",
 		t: Tuple{
@@ -240,7 +248,8 @@ func Test_resourceOrdering_order(t *testing.T) {
 		},
 	}}
 
-	for _, test := range tests {
+	for i := range tests {
+		test := &tests[i]
 		t.Run(test.name, func(t *testing.T) {
 			got := test.ro.order()
 			if diff := cmp.Diff(test.want, got); diff != "" {
@@ -430,3 +439,236 @@ func TestFromState(t *testing.T) {
 		})
 	}
 }
+
+func TestResourceMapDependencyAddresses(t *testing.T) {
+	rm := ResourceMap{
+		"t.foo": {Type: "t", Name: "foo"},
+		"t.bar[\"a\"]": {
+			Type:         "t",
+			Name:         "bar",
+			IndexKey:     "a",
+			Dependencies: []string{"t.foo"},
+		},
+		"t.bar[\"b\"]": {
+			Type:         "t",
+			Name:         "bar",
+			IndexKey:     "b",
+			Dependencies: []string{"t.foo", "data.t.ignored"},
+		},
+		"t.baz": {
+			Type:         "t",
+			Name:         "baz",
+			Dependencies: []string{"t.bar", "t.missing"},
+		},
+		"t.count[0]": {Type: "t", Name: "count", IndexKey: float64(0)},
+		"t.count[1]": {Type: "t", Name: "count", IndexKey: float64(1)},
+		"t.qux": {
+			Type:         "t",
+			Name:         "qux",
+			Dependencies: []string{"t.count"},
+		},
+	}
+	want := map[string][]string{
+		"t.bar[\"a\"]": {"t.foo"},
+		"t.bar[\"b\"]": {"t.foo"},
+		"t.baz":        {"t.bar[\"a\"]", "t.bar[\"b\"]"},
+		"t.qux":        {"t.count[0]", "t.count[1]"},
+	}
+
+	got := rm.DependencyAddresses()
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("DependencyAddresses() return mismatch (-want, +got):", diff)
+	}
+}
+
+func TestResourceMapCollection(t *testing.T) {
+	rm := ResourceMap{
+		`t.bar["a"]`:                  {Type: "t", Name: "bar", IndexKey: "a"},
+		`t.bar["b"]`:                  {Type: "t", Name: "bar", IndexKey: "b"},
+		`t.bar[0]`:                    {Type: "t", Name: "bar", IndexKey: float64(0)},
+		`t.barbaz["a"]`:               {Type: "t", Name: "barbaz", IndexKey: "a"},
+		`module.m.t.bar["a"]`:         {Module: "module.m", Type: "t", Name: "bar", IndexKey: "a"},
+		`module.m["x"].t.bar["a"]`:    {Module: `module.m["x"]`, Type: "t", Name: "bar", IndexKey: "a"},
+		`module.m["y"].t.bar["a"]`:    {Module: `module.m["y"]`, Type: "t", Name: "bar", IndexKey: "a"},
+		`module.n.module.m.t.bar[""]`: {Module: "module.n.module.m", Type: "t", Name: "bar", IndexKey: ""},
+	}
+	for _, tt := range []struct {
+		address string
+		want    []string
+	}{
+		{"t.bar", []string{`t.bar["a"]`, `t.bar["b"]`}},
+		{"module.m.t.bar", []string{`module.m.t.bar["a"]`}},
+		{`module.m["x"].t.bar`, []string{`module.m["x"].t.bar["a"]`}},
+		{"t.missing", nil},
+	} {
+		var got []string
+		for _, r := range rm.Collection(tt.address) {
+			got = append(got, r.Address())
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("Collection(%q) return mismatch (-want, +got): %s", tt.address, diff)
+		}
+	}
+}
+
+func TestResourceMapOrderCycle(t *testing.T) {
+	rm := ResourceMap{
+		"t.foo": {Type: "t", Name: "foo", Dependencies: []string{"t.bar"}},
+		"t.bar": {Type: "t", Name: "bar", Dependencies: []string{"t.foo"}},
+	}
+
+	if _, err := rm.Order(); err == nil {
+		t.Error("Order() of a cycle succeeded, want error")
+	}
+}
+
+func TestCollect(t *testing.T) {
+	st := state.V4{
+		Resources: []state.Resource{{
+			Mode: "managed",
+			Type: "t",
+			Name: "ok",
+			Instances: []state.Instance{{
+				Attributes: map[string]interface{}{"id": "ok-id"},
+				SensitiveAttributes: []state.Path{{
+					{Type: "get_attr", Value: "rule"},
+					{Type: "index", Value: map[string]interface{}{"value": float64(0), "type": "number"}},
+					{Type: "get_attr", Value: "password"},
+				}},
+			}},
+		}, {
+			Mode: "managed",
+			Type: "t",
+			Name: "no-id",
+			Instances: []state.Instance{{
+				IndexKey:   "k",
+				Attributes: map[string]interface{}{"name": "x"},
+			}},
+		}, {
+			Module: "module.m",
+			Mode:   "managed",
+			Type:   "t",
+			Name:   "numeric-id",
+			Instances: []state.Instance{{
+				Attributes: map[string]interface{}{"id": float64(5)},
+			}, {
+				IndexKey:   float64(1),
+				Attributes: map[string]interface{}{"id": float64(1234567890123456)},
+			}},
+		}, {
+			Mode: "managed",
+			Type: "t",
+			Name: "list-id",
+			Instances: []state.Instance{{
+				Attributes: map[string]interface{}{"id": []interface{}{"a"}},
+			}},
+		}, {
+			Mode: "managed",
+			Type: "framework",
+			Name: "no-id",
+			Instances: []state.Instance{{
+				Attributes: map[string]interface{}{"name": "n", "region": "r"},
+			}, {
+				IndexKey:   "missing",
+				Attributes: map[string]interface{}{"name": "n"},
+			}},
+		}, {
+			Mode: "data",
+			Type: "t",
+			Name: "data-no-id",
+			Instances: []state.Instance{{
+				Attributes: map[string]interface{}{},
+			}},
+		}},
+	}
+
+	// Numbers above 2^53 are only exact in the state as it was read.
+	large, err := state.Parse([]byte(`{"version": 4, "resources": [{"mode": "managed", "type": "t", "name": "large-id", "instances": [{"attributes": {"id": 9007199254740993}}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Resources = append(st.Resources, large.Resources...)
+
+	saved := rules
+	t.Cleanup(func() { rules = saved })
+	rule, err := TemplateRule("framework", "{region}/{name}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	AddRules(rule)
+
+	rm, skipped := Collect(st, "")
+	ids := map[string]string{}
+	for a, r := range rm {
+		ids[a] = r.ImportableID()
+	}
+	wantIDs := map[string]string{
+		"t.ok":                     "ok-id",
+		"module.m.t.numeric-id":    "5",
+		"module.m.t.numeric-id[1]": "1234567890123456",
+		"t.large-id":               "9007199254740993",
+		"framework.no-id":          "r/n",
+	}
+	if diff := cmp.Diff(wantIDs, ids); diff != "" {
+		t.Error("Collect() import IDs mismatch (-want, +got):", diff)
+	}
+	if diff := cmp.Diff([]string{"rule[0].password"}, rm["t.ok"].Sensitive); diff != "" {
+		t.Error("Collect() sensitive paths mismatch (-want, +got):", diff)
+	}
+	want := []Skipped{
+		{Address: "t.no-id[\"k\"]", Reason: "resource doesn't have an id attribute, and no import rule for its type builds one from other attributes"},
+		{Address: "t.list-id", Reason: "resource id [a] isn't a string or a number"},
+		{Address: `framework.no-id["missing"]`, Reason: `import rule framework (template "{region}/{name}") needs the missing attributes region`},
+	}
+	if diff := cmp.Diff(want, skipped); diff != "" {
+		t.Error("Collect() skipped mismatch (-want, +got):", diff)
+	}
+}
+
+func TestTaintedAndDeposed(t *testing.T) {
+	st := state.V4{
+		Resources: []state.Resource{{
+			Mode:     "managed",
+			Type:     "t",
+			Name:     "replaced",
+			Provider: "provider[\"registry.terraform.io/hashicorp/a\"]",
+			Instances: []state.Instance{{
+				Status:     "tainted",
+				Attributes: map[string]interface{}{"id": "new"},
+			}, {
+				Deposed:    "00000002",
+				Attributes: map[string]interface{}{"id": "older"},
+			}, {
+				Deposed:    "00000001",
+				Attributes: map[string]interface{}{"id": "old"},
+			}},
+		}, {
+			Mode:     "managed",
+			Type:     "t",
+			Name:     "other",
+			Provider: "provider[\"registry.terraform.io/hashicorp/b\"]",
+			Instances: []state.Instance{{
+				IndexKey:   float64(0),
+				Deposed:    "00000001",
+				Attributes: map[string]interface{}{"id": "b"},
+			}},
+		}},
+	}
+
+	rm, skipped := Collect(st, "")
+	if r, ok := rm["t.replaced"]; !ok || r.ID != "new" || !r.Tainted || len(rm) != 1 || len(skipped) != 0 {
+		t.Errorf("Collect() = %v, %v, want only the tainted current object of t.replaced", rm, skipped)
+	}
+
+	want := []DeposedObject{
+		{Address: "t.other[0]", Key: "00000001", ID: "b"},
+		{Address: "t.replaced", Key: "00000001", ID: "old"},
+		{Address: "t.replaced", Key: "00000002", ID: "older"},
+	}
+	if diff := cmp.Diff(want, Deposed(st, "")); diff != "" {
+		t.Error("Deposed() return mismatch (-want, +got):", diff)
+	}
+	if diff := cmp.Diff(want[1:], Deposed(st, "hashicorp/a")); diff != "" {
+		t.Error("Deposed() with provider mismatch (-want, +got):", diff)
+	}
+}
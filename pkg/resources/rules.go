@@ -0,0 +1,149 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Rule describes how the import ID of a resource is derived.
+type Rule struct {
+	// Name identifies the rule, usually by the resource types it matches.
+	Name string
+	// Attributes are the attributes the import ID is built from, in order.
+	Attributes []string
+
+	match func(Tuple) bool
+	build func(Tuple) string
+}
+
+// rules are checked in order, the first match builds the import ID. The
+// last rule matches every resource.
+var rules = []Rule{{
+	// TODO: condition
+	Name:       "google_project_iam_member",
+	Attributes: []string{"project", "role", "member"},
+	match:      typeIs("google_project_iam_member"),
+}, {
+	Name:       "google_secret_manager_secret_iam_member",
+	Attributes: []string{"secret_id", "role", "member"},
+	match:      typeIs("google_secret_manager_secret_iam_member"),
+}, {
+	Name:       "*_iam_member",
+	Attributes: []string{"name", "role", "member"},
+	match: func(r Tuple) bool {
+		return strings.HasSuffix(r.Type, "_iam_member")
+	},
+}, {
+	// TODO: condition
+	Name:       "google_storage_bucket_iam_binding",
+	Attributes: []string{"bucket", "role"},
+	match:      typeIs("google_storage_bucket_iam_binding"),
+	build: func(r Tuple) string {
+		bucket, _ := r.Attributes["bucket"].(string)
+		return fmt.Sprintf("%s %s", strings.TrimPrefix(bucket, "b/"), FormatValue(r.Attributes["role"]))
+	},
+}, {
+	Name:       "default",
+	Attributes: []string{"id"},
+	match:      func(Tuple) bool { return true },
+	build:      func(r Tuple) string { return r.ID },
+}}
+
+// AddRules adds rules that take precedence over the built-in rules, in order.
+func AddRules(rs ...Rule) {
+	rules = append(append([]Rule{}, rs...), rules...)
+}
+
+var placeholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// TemplateRule returns a rule for resources of the given type that builds the
+// import ID from a template such as `{project}/{name}`, where each placeholder
+// is replaced by the attribute of that name.
+func TemplateRule(typ, template string) (Rule, error) {
+	var attributes []string
+	for _, m := range placeholder.FindAllStringSubmatch(template, -1) {
+		if m[1] == "" {
+			return Rule{}, fmt.Errorf("rule for %s: empty placeholder in %q", typ, template)
+		}
+		attributes = append(attributes, m[1])
+	}
+	if rest := placeholder.ReplaceAllString(template, ""); strings.ContainsAny(rest, "{}") {
+		return Rule{}, fmt.Errorf("rule for %s: unbalanced braces in %q", typ, template)
+	}
+
+	return Rule{
+		Name:       fmt.Sprintf("%s (template %q)", typ, template),
+		Attributes: attributes,
+		match:      typeIs(typ),
+		build: func(r Tuple) string {
+			return placeholder.ReplaceAllStringFunc(template, func(m string) string {
+				return FormatValue(r.Attributes[m[1:len(m)-1]])
+			})
+		},
+	}, nil
+}
+
+func typeIs(t string) func(Tuple) bool {
+	return func(r Tuple) bool {
+		return r.Type == t
+	}
+}
+
+// ImportRule returns the import ID of the resource along with the rule that
+// produced it.
+func (r Tuple) ImportRule() (string, Rule) {
+	for _, rule := range rules {
+		if !rule.match(r) {
+			continue
+		}
+		if rule.build != nil {
+			return rule.build(r), rule
+		}
+		parts := make([]string, len(rule.Attributes))
+		for i, a := range rule.Attributes {
+			parts[i] = FormatValue(r.Attributes[a])
+		}
+		return strings.Join(parts, " "), rule
+	}
+	// Unreachable, the default rule matches everything.
+	return r.ID, Rule{Name: "default"}
+}
+
+// MissingAttributes returns the attributes the rule builds the import ID from
+// that the resource doesn't have, or has as null or "". The default rule uses
+// the ID of the resource rather than its attributes.
+func (r Tuple) MissingAttributes(rule Rule) []string {
+	if rule.Name == "default" {
+		if r.ID == "" {
+			return []string{"id"}
+		}
+		return nil
+	}
+	var missing []string
+	for _, a := range rule.Attributes {
+		if v, ok := r.Attributes[a]; !ok || v == nil || v == "" {
+			missing = append(missing, a)
+		}
+	}
+	return missing
+}
+
+// FormatValue returns an attribute value as it appears in an import ID.
+// Numbers are written in full, without an exponent, and exact numbers with
+// every digit they were read with.
+func FormatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,164 @@
+package resources
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTupleImportRule(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		t         Tuple
+		wantID    string
+		wantRule  string
+		wantAttrs []string
+	}{{
+		name: "specific type",
+		t: Tuple{
+			Type: "google_project_iam_member",
+			Attributes: map[string]interface{}{
+				"project": "p",
+				"role":    "r",
+				"member":  "m",
+			},
+		},
+		wantID:    "p r m",
+		wantRule:  "google_project_iam_member",
+		wantAttrs: []string{"project", "role", "member"},
+	}, {
+		name: "suffix",
+		t: Tuple{
+			Type: "google_pubsub_topic_iam_member",
+			Attributes: map[string]interface{}{
+				"name":   "n",
+				"role":   "r",
+				"member": "m",
+			},
+		},
+		wantID:    "n r m",
+		wantRule:  "*_iam_member",
+		wantAttrs: []string{"name", "role", "member"},
+	}, {
+		name: "custom build",
+		t: Tuple{
+			Type: "google_storage_bucket_iam_binding",
+			Attributes: map[string]interface{}{
+				"bucket": "b/bucket",
+				"role":   "r",
+			},
+		},
+		wantID:    "bucket r",
+		wantRule:  "google_storage_bucket_iam_binding",
+		wantAttrs: []string{"bucket", "role"},
+	}, {
+		name:      "default",
+		t:         Tuple{Type: "foo", ID: "foo-id"},
+		wantID:    "foo-id",
+		wantRule:  "default",
+		wantAttrs: []string{"id"},
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			id, rule := tt.t.ImportRule()
+			if diff := cmp.Diff(tt.wantID, id); diff != "" {
+				t.Error("ImportRule() id mismatch (-want, +got):", diff)
+			}
+			if diff := cmp.Diff(tt.wantRule, rule.Name); diff != "" {
+				t.Error("ImportRule() rule mismatch (-want, +got):", diff)
+			}
+			if diff := cmp.Diff(tt.wantAttrs, rule.Attributes); diff != "" {
+				t.Error("ImportRule() attributes mismatch (-want, +got):", diff)
+			}
+		})
+	}
+}
+
+func TestTemplateRule(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		template  string
+		wantID    string
+		wantAttrs []string
+		wantErr   bool
+	}{{
+		name:      "attributes",
+		template:  "{project}/{name}",
+		wantID:    "p/n",
+		wantAttrs: []string{"project", "name"},
+	}, {
+		name:      "non-string attribute",
+		template:  "{name}:{port}",
+		wantID:    "n:8080",
+		wantAttrs: []string{"name", "port"},
+	}, {
+		name:      "exact number",
+		template:  "{number}/{name}",
+		wantID:    "9007199254740993/n",
+		wantAttrs: []string{"number", "name"},
+	}, {
+		name:     "empty placeholder",
+		template: "{}/{name}",
+		wantErr:  true,
+	}, {
+		name:     "unbalanced",
+		template: "{project/{name}",
+		wantErr:  true,
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := TemplateRule("t", tt.template)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TemplateRule() error = %v, want error: %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			r := Tuple{Type: "t", Attributes: map[string]interface{}{"project": "p", "name": "n", "port": float64(8080), "number": json.Number("9007199254740993")}}
+			if !rule.match(r) || rule.match(Tuple{Type: "other"}) {
+				t.Error("rule doesn't match only its type")
+			}
+			if diff := cmp.Diff(tt.wantID, rule.build(r)); diff != "" {
+				t.Error("build() mismatch (-want, +got):", diff)
+			}
+			if diff := cmp.Diff(tt.wantAttrs, rule.Attributes); diff != "" {
+				t.Error("Attributes mismatch (-want, +got):", diff)
+			}
+		})
+	}
+}
+
+func TestAddRules(t *testing.T) {
+	saved := rules
+	t.Cleanup(func() { rules = saved })
+
+	rule, err := TemplateRule("foo_resource", "{name}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	AddRules(rule)
+
+	r := Tuple{Type: "foo_resource", ID: "foo-id", Attributes: map[string]interface{}{"name": "custom"}}
+	if got := r.ImportableID(); got != "custom" {
+		t.Errorf("ImportableID() = %q, want the added rule to take precedence", got)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	for _, tt := range []struct {
+		v    interface{}
+		want string
+	}{
+		{"s", "s"},
+		{nil, ""},
+		{float64(8080), "8080"},
+		{float64(1e21), "1000000000000000000000"},
+		{float64(3257823384035534), "3257823384035534"},
+		{json.Number("9007199254740993"), "9007199254740993"},
+		{0.5, "0.5"},
+		{true, "true"},
+	} {
+		if got := FormatValue(tt.v); got != tt.want {
+			t.Errorf("FormatValue(%#v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package rollback
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+)
+
+const (
+	// StateFile is the name of the copy of the original state.
+	StateFile = "terraform.tfstate.orig"
+	// ScriptFile is the name of the script that pushes the original state back.
+	ScriptFile = "rollback.sh"
+	// TouchedFile is the name of the list of resources the migration touches.
+	TouchedFile = "touched.txt"
+)
+
+// Write creates the rollback artifact in dir: a byte-for-byte copy of the original
+// state, a script that restores it, and the addresses of the touched resources.
+// Existing artifacts in dir are never overwritten, so a second run can't clobber
+// the only good copy of the state.
+func Write(dir string, original []byte, st state.V4, touched []*resources.Tuple) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	for _, name := range []string{StateFile, ScriptFile, TouchedFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return fmt.Errorf("rollback artifact %s already exists", filepath.Join(dir, name))
+		}
+	}
+
+	if err := writeFile(filepath.Join(dir, StateFile), original, 0o600); err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(dir, ScriptFile), []byte(Script(st)), 0o755); err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, TouchedFile), []byte(touchedList(st, touched)), 0o644)
+}
+
+func writeFile(name string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	// The copy of the state is only useful if it survives a crash mid-migration.
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+const script = `#!/bin/sh
+# Restores the state captured by tf-state-import before migrating.
+# Run from the Terraform working directory. Set TF=tofu to use OpenTofu.
+#
+# The current state must have the same lineage as the original and a serial
+# no lower than the original, otherwise it is not the state that was migrated
+# and pushing over it would lose data.
+set -eu
+
+here=$(cd "$(dirname "$0")" && pwd)
+tf=${TF:-terraform}
+expected_lineage='%s'
+expected_serial=%d
+
+current=$(mktemp)
+trap 'rm -f "$current"' EXIT
+"$tf" state pull > "$current"
+
+lineage=$(sed -n 's/.*"lineage": *"\([^"]*\)".*/\1/p' "$current" | head -n 1)
+serial=$(sed -n 's/.*"serial": *\([0-9]*\).*/\1/p' "$current" | head -n 1)
+
+if [ "$lineage" != "$expected_lineage" ]; then
+  echo "refusing to roll back: state lineage is '$lineage', expected '$expected_lineage'" >&2
+  exit 1
+fi
+if [ "${serial:-0}" -lt "$expected_serial" ]; then
+  echo "refusing to roll back: state serial $serial is older than the original serial $expected_serial" >&2
+  exit 1
+fi
+
+"$tf" state push -force "$here/%s"
+echo "restored state lineage $expected_lineage serial $expected_serial"
+`
+
+// Script returns the rollback script for the given original state.
+func Script(st state.V4) string {
+	// Lineages are UUIDs, but never let a quote break out of the shell string.
+	lineage := strings.ReplaceAll(st.Lineage, "'", "")
+	return fmt.Sprintf(script, lineage, st.Serial, StateFile)
+}
+
+func touchedList(st state.V4, touched []*resources.Tuple) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Resources removed and re-imported from lineage %s serial %d\n", st.Lineage, st.Serial)
+	for _, r := range touched {
+		fmt.Fprintf(&b, "%s\t%s\n", r.Address(), r.ImportableID())
+	}
+	return b.String()
+}
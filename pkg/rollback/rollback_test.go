@@ -0,0 +1,133 @@
+package rollback
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+)
+
+const original = `{
+  "version": 4,
+  "serial": 7,
+  "lineage": "a1b2c3",
+  "resources": []
+}
+`
+
+func TestWrite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "rollback")
+	st := state.V4{Version: 4, Serial: 7, Lineage: "a1b2c3"}
+	touched := []*resources.Tuple{
+		{Type: "t", Name: "foo", ID: "foo-id"},
+		{Type: "t", Name: "bar", ID: "bar-id", IndexKey: "k"},
+	}
+
+	if err := Write(dir, []byte(original), st, touched); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, StateFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(original, string(got)); diff != "" {
+		t.Errorf("state copy mismatch (-want, +got):\n%s", diff)
+	}
+
+	got, err = os.ReadFile(filepath.Join(dir, TouchedFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Resources removed and re-imported from lineage a1b2c3 serial 7\n" +
+		"t.foo\tfoo-id\n" +
+		"t.bar[\"k\"]\tbar-id\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("touched list mismatch (-want, +got):\n%s", diff)
+	}
+
+	if err := Write(dir, []byte(original), st, touched); err == nil {
+		t.Error("Write() over an existing artifact succeeded, want error")
+	}
+}
+
+func TestScript(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no shell available")
+	}
+
+	for _, tt := range []struct {
+		name       string
+		current    string
+		wantPushed bool
+	}{{
+		name:       "same lineage, newer serial",
+		current:    `{"version": 4, "serial": 9, "lineage": "a1b2c3"}`,
+		wantPushed: true,
+	}, {
+		name:       "same lineage, same serial",
+		current:    `{"version": 4, "serial": 7, "lineage": "a1b2c3"}`,
+		wantPushed: true,
+	}, {
+		name:    "different lineage",
+		current: `{"version": 4, "serial": 9, "lineage": "other"}`,
+	}, {
+		name:    "older serial",
+		current: `{"version": 4, "serial": 3, "lineage": "a1b2c3"}`,
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			dir := filepath.Join(tmp, "rollback")
+			st := state.V4{Version: 4, Serial: 7, Lineage: "a1b2c3"}
+			if err := Write(dir, []byte(original), st, nil); err != nil {
+				t.Fatalf("Write() = %v", err)
+			}
+
+			// A stub terraform that serves the current state and records pushes.
+			pushed := filepath.Join(tmp, "pushed")
+			stub := filepath.Join(tmp, "terraform")
+			if err := os.WriteFile(filepath.Join(tmp, "current"), []byte(tt.current), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			stubScript := "#!/bin/sh\n" +
+				"case \"$2\" in\n" +
+				"pull) cat '" + filepath.Join(tmp, "current") + "' ;;\n" +
+				"push) echo \"$@\" > '" + pushed + "' ;;\n" +
+				"esac\n"
+			if err := os.WriteFile(stub, []byte(stubScript), 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			cmd := exec.Command("sh", filepath.Join(dir, ScriptFile))
+			cmd.Env = append(os.Environ(), "TF="+stub)
+			out, err := cmd.CombinedOutput()
+			if tt.wantPushed && err != nil {
+				t.Fatalf("rollback script failed: %v\n%s", err, out)
+			}
+			if !tt.wantPushed && err == nil {
+				t.Fatalf("rollback script succeeded, want failure\n%s", out)
+			}
+
+			got, err := os.ReadFile(pushed)
+			if !tt.wantPushed {
+				if err == nil {
+					t.Errorf("state was pushed: %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("state was not pushed: %v", err)
+			}
+			want := "state push -force " + filepath.Join(dir, StateFile)
+			if diff := cmp.Diff(want, strings.TrimSpace(string(got))); diff != "" {
+				t.Errorf("push mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultRegistry is the host of provider sources that don't name one.
+const DefaultRegistry = "registry.terraform.io"
+
+// ProviderConfig is the provider configuration of a resource as state records
+// it, e.g. `module.api.provider["registry.terraform.io/hashicorp/google"].eu`.
+type ProviderConfig struct {
+	// Module is the module the provider is configured in, empty for the root
+	// module.
+	Module string
+	// Source is the fully qualified source address of the provider.
+	Source string
+	Alias  string
+}
+
+// ParseProviderConfig parses the provider of a resource in state.
+func ParseProviderConfig(s string) (ProviderConfig, error) {
+	const open, closing = `provider["`, `"]`
+	i := strings.Index(s, open)
+	if i < 0 || i > 0 && s[i-1] != '.' {
+		return ProviderConfig{}, fmt.Errorf("unsupported provider configuration %q", s)
+	}
+	rest := s[i+len(open):]
+	j := strings.Index(rest, closing)
+	if j < 0 {
+		return ProviderConfig{}, fmt.Errorf("unsupported provider configuration %q", s)
+	}
+	p := ProviderConfig{Module: strings.TrimSuffix(s[:i], "."), Source: rest[:j]}
+	if alias := rest[j+len(closing):]; alias != "" {
+		if !strings.HasPrefix(alias, ".") || len(alias) == 1 {
+			return ProviderConfig{}, fmt.Errorf("unsupported provider configuration %q", s)
+		}
+		p.Alias = alias[1:]
+	}
+	return p, nil
+}
+
+func (p ProviderConfig) String() string {
+	s := fmt.Sprintf("provider[%q]", p.Source)
+	if p.Module != "" {
+		s = p.Module + "." + s
+	}
+	if p.Alias != "" {
+		s += "." + p.Alias
+	}
+	return s
+}
+
+// NormalizeSource returns the fully qualified form of a provider source
+// address, the way Terraform resolves `google` and `hashicorp/google` to
+// `registry.terraform.io/hashicorp/google`.
+func NormalizeSource(source string) string {
+	switch strings.Count(source, "/") {
+	case 0:
+		return DefaultRegistry + "/hashicorp/" + source
+	case 1:
+		return DefaultRegistry + "/" + source
+	}
+	return source
+}
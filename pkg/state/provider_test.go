@@ -0,0 +1,62 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseProviderConfig(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		s       string
+		want    ProviderConfig
+		wantErr bool
+	}{{
+		name: "root",
+		s:    `provider["registry.terraform.io/hashicorp/google"]`,
+		want: ProviderConfig{Source: "registry.terraform.io/hashicorp/google"},
+	}, {
+		name: "module and alias",
+		s:    `module.api.module.gclb[0].provider["registry.terraform.io/hashicorp/google"].eu`,
+		want: ProviderConfig{Module: "module.api.module.gclb[0]", Source: "registry.terraform.io/hashicorp/google", Alias: "eu"},
+	}, {
+		name:    "legacy",
+		s:       "provider.google",
+		wantErr: true,
+	}, {
+		name:    "bad alias",
+		s:       `provider["registry.terraform.io/hashicorp/google"]eu`,
+		wantErr: true,
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProviderConfig(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseProviderConfig() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error("ParseProviderConfig() return mismatch (-want, +got):", diff)
+			}
+			if s := got.String(); s != tt.s {
+				t.Errorf("String() = %q, want %q", s, tt.s)
+			}
+		})
+	}
+}
+
+func TestNormalizeSource(t *testing.T) {
+	for _, tt := range []struct {
+		source, want string
+	}{
+		{"google", "registry.terraform.io/hashicorp/google"},
+		{"chainguard-dev/chainguard", "registry.terraform.io/chainguard-dev/chainguard"},
+		{"registry.opentofu.org/hashicorp/google", "registry.opentofu.org/hashicorp/google"},
+	} {
+		if got := NormalizeSource(tt.source); got != tt.want {
+			t.Errorf("NormalizeSource(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package state
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is the SHA-256 of an empty request body.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// Credentials are AWS style access keys.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// S3Source reads state from an object in S3 or S3 compatible storage, such as
+// MinIO, signing the request with AWS Signature Version 4.
+type S3Source struct {
+	Bucket string
+	Key    string
+	Region string
+	// Endpoint is the base URL of an S3 compatible service, objects are then
+	// addressed path style. If empty, AWS S3 in Region is used.
+	Endpoint string
+	// Credentials sign the request. If empty the request is anonymous.
+	Credentials Credentials
+	Client      *http.Client
+
+	now func() time.Time
+}
+
+// NewS3Source returns a source for s3://bucket/key. The region and endpoint
+// may be given as the query parameters `region` and `endpoint`, otherwise they
+// are taken from AWS_REGION (or AWS_DEFAULT_REGION) and AWS_ENDPOINT_URL_S3 (or
+// AWS_ENDPOINT_URL). Credentials are taken from AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+func NewS3Source(u *url.URL) (S3Source, error) {
+	key := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || key == "" {
+		return S3Source{}, fmt.Errorf("%s: expected s3://bucket/key", u)
+	}
+	q := u.Query()
+	return S3Source{
+		Bucket:   u.Host,
+		Key:      key,
+		Region:   firstNonEmpty(q.Get("region"), os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1"),
+		Endpoint: firstNonEmpty(q.Get("endpoint"), os.Getenv("AWS_ENDPOINT_URL_S3"), os.Getenv("AWS_ENDPOINT_URL")),
+		Credentials: Credentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		},
+	}, nil
+}
+
+func firstNonEmpty(ss ...string) string {
+	for _, s := range ss {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func (s S3Source) Read(ctx context.Context) ([]byte, error) {
+	u, err := s.objectURL()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.Credentials.AccessKeyID != "" {
+		now := time.Now
+		if s.now != nil {
+			now = s.now
+		}
+		signV4(req, s.Credentials, s.Region, "s3", emptyPayloadHash, now())
+	}
+	return get(s.Client, req, s)
+}
+
+func (s S3Source) String() string {
+	return "s3://" + s.Bucket + "/" + s.Key
+}
+
+func (s S3Source) objectURL() (*url.URL, error) {
+	key := "/" + s.Key
+	u := &url.URL{Scheme: "https", Host: fmt.Sprintf("%s.s3.%s.amazonaws.com", s.Bucket, s.Region)}
+	if s.Endpoint != "" {
+		var err error
+		if u, err = url.Parse(strings.TrimSuffix(s.Endpoint, "/")); err != nil {
+			return nil, fmt.Errorf("invalid S3 endpoint: %w", err)
+		}
+		key = u.Path + "/" + s.Bucket + key
+	}
+	u.Path = key
+	u.RawPath = uriEncode(key, false)
+	return u, nil
+}
+
+// signV4 adds the AWS Signature Version 4 headers to req, signing the host and
+// every header already set on it.
+func signV4(req *http.Request, creds Credentials, region, service, payloadHash string, t time.Time) {
+	amzDate := t.UTC().Format("20060102T150405Z")
+	scope := strings.Join([]string{amzDate[:8], region, service, "aws4_request"}, "/")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for k, vs := range req.Header {
+		headers[strings.ToLower(k)] = strings.Join(strings.Fields(strings.Join(vs, ",")), " ")
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", k, headers[k])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256(canonicalRequest),
+	}, "\n")
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{amzDate[:8], region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func canonicalQuery(q url.Values) string {
+	var params []string
+	for k, vs := range q {
+		for _, v := range vs {
+			params = append(params, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// uriEncode percent-encodes every byte except the unreserved characters, as
+// SigV4 requires. Slashes are kept unless encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hexSHA256(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
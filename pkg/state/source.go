@@ -0,0 +1,139 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// ErrNoState is returned by a StateSource that has no state stored yet.
+var ErrNoState = errors.New("no state")
+
+// StateSource is somewhere a state file can be read from, such as a local file
+// or a Terraform backend.
+type StateSource interface {
+	// Read returns the raw contents of the state.
+	Read(ctx context.Context) ([]byte, error)
+	// String returns the location of the state, for messages.
+	String() string
+}
+
+// OpenSource returns the source for location, selected by its scheme:
+//
+//	path/to/terraform.tfstate, file:///path/to/terraform.tfstate
+//	http://host/state, https://host/state   Terraform HTTP backend
+//	s3://bucket/key                         S3 compatible object storage
+//
+// Credentials are taken from the same environment variables Terraform uses,
+// see NewHTTPSource and NewS3Source.
+func OpenSource(location string) (StateSource, error) {
+	u, err := url.Parse(location)
+	// Windows paths and relative paths with colons aren't URLs.
+	if err != nil || len(u.Scheme) <= 1 {
+		return FileSource{Path: location}, nil
+	}
+
+	switch u.Scheme {
+	case "file":
+		return FileSource{Path: u.Path}, nil
+	case "http", "https":
+		return NewHTTPSource(location), nil
+	case "s3":
+		s, err := NewS3Source(u)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("%s: unsupported state location scheme %q", location, u.Scheme)
+}
+
+// Read reads and parses the state at location.
+func Read(ctx context.Context, location string) ([]byte, V4, error) {
+	src, err := OpenSource(location)
+	if err != nil {
+		return nil, V4{}, err
+	}
+	raw, err := src.Read(ctx)
+	if err != nil {
+		return nil, V4{}, err
+	}
+	st, err := Parse(raw)
+	if err != nil {
+		return nil, V4{}, fmt.Errorf("%s: %w", src, err)
+	}
+	return raw, st, nil
+}
+
+// FileSource reads a state file from disk.
+type FileSource struct {
+	Path string
+}
+
+func (s FileSource) Read(context.Context) ([]byte, error) {
+	return os.ReadFile(s.Path)
+}
+
+func (s FileSource) String() string {
+	return s.Path
+}
+
+// HTTPSource reads state using the Terraform HTTP backend protocol, which is a
+// GET of the state address.
+type HTTPSource struct {
+	Address  string
+	Username string
+	Password string
+	Client   *http.Client
+}
+
+// NewHTTPSource returns a source for the state at address, using the basic
+// auth credentials in TF_HTTP_USERNAME and TF_HTTP_PASSWORD if they're set.
+func NewHTTPSource(address string) HTTPSource {
+	return HTTPSource{
+		Address:  address,
+		Username: os.Getenv("TF_HTTP_USERNAME"),
+		Password: os.Getenv("TF_HTTP_PASSWORD"),
+	}
+}
+
+func (s HTTPSource) Read(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.Address, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.Username != "" || s.Password != "" {
+		req.SetBasicAuth(s.Username, s.Password)
+	}
+	return get(s.Client, req, s)
+}
+
+func (s HTTPSource) String() string {
+	return s.Address
+}
+
+// get performs the request and returns the body of a successful response.
+// Backends respond with no content or not found when there is no state yet.
+func get(client *http.Client, req *http.Request, src StateSource) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(resp.Body)
+	case http.StatusNoContent, http.StatusNotFound:
+		return nil, fmt.Errorf("%s: %w", src, ErrNoState)
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("%s: unexpected response %s: %s", src, resp.Status, body)
+}
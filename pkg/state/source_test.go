@@ -0,0 +1,109 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestOpenSource(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	t.Setenv("AWS_ENDPOINT_URL_S3", "")
+	t.Setenv("AWS_ENDPOINT_URL", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("TF_HTTP_USERNAME", "")
+	t.Setenv("TF_HTTP_PASSWORD", "")
+
+	for _, tt := range []struct {
+		location string
+		want     StateSource
+		wantErr  bool
+	}{
+		{location: "terraform.tfstate", want: FileSource{Path: "terraform.tfstate"}},
+		{location: "/abs/terraform.tfstate", want: FileSource{Path: "/abs/terraform.tfstate"}},
+		{location: `C:\state\terraform.tfstate`, want: FileSource{Path: `C:\state\terraform.tfstate`}},
+		{location: "file:///abs/terraform.tfstate", want: FileSource{Path: "/abs/terraform.tfstate"}},
+		{location: "https://state.example.com/prod", want: HTTPSource{Address: "https://state.example.com/prod"}},
+		{location: "s3://bucket/env/prod.tfstate", want: S3Source{Bucket: "bucket", Key: "env/prod.tfstate", Region: "us-east-1"}},
+		{
+			location: "s3://bucket/prod.tfstate?region=eu-west-1&endpoint=http://localhost:9000",
+			want:     S3Source{Bucket: "bucket", Key: "prod.tfstate", Region: "eu-west-1", Endpoint: "http://localhost:9000"},
+		},
+		{location: "s3://bucket", wantErr: true},
+		{location: "gcs://bucket/key", wantErr: true},
+	} {
+		t.Run(tt.location, func(t *testing.T) {
+			got, err := OpenSource(tt.location)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("OpenSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got, cmpopts.IgnoreUnexported(S3Source{})); diff != "" {
+				t.Error("OpenSource() return mismatch (-want, +got):", diff)
+			}
+		})
+	}
+}
+
+func TestHTTPSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "tf" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/prod":
+			w.Write([]byte(`{"version": 4, "serial": 3}`))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	t.Setenv("TF_HTTP_USERNAME", "tf")
+	t.Setenv("TF_HTTP_PASSWORD", "secret")
+
+	_, st, err := Read(context.Background(), srv.URL+"/prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(V4{Version: 4, Serial: 3}, st); diff != "" {
+		t.Error("Read() return mismatch (-want, +got):", diff)
+	}
+
+	if _, err := NewHTTPSource(srv.URL + "/empty").Read(context.Background()); !errors.Is(err, ErrNoState) {
+		t.Errorf("Read() of an empty state = %v, want ErrNoState", err)
+	}
+	if _, err := NewHTTPSource(srv.URL + "/broken").Read(context.Background()); err == nil {
+		t.Error("Read() of a failing backend succeeded")
+	}
+	if _, err := (HTTPSource{Address: srv.URL + "/prod"}).Read(context.Background()); err == nil {
+		t.Error("Read() without credentials succeeded")
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	raw, st, err := Read(context.Background(), "testdata/example.tfstate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ParseStateFile("testdata/example.tfstate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, st, cmpopts.IgnoreUnexported(Instance{})); diff != "" {
+		t.Error("Read() return mismatch (-want, +got):", diff)
+	}
+	if len(raw) == 0 {
+		t.Error("Read() returned no raw state")
+	}
+}
@@ -1,28 +1,216 @@
 package state
 
 import (
+	"bytes"
+	"crypto/rand"
 	"encoding/json"
+	"fmt"
 	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
+// V4 is a version 4 state file. Its fields follow the state file format
+// closely enough that Marshal writes back what Parse read.
 type V4 struct {
-	Resources []Resource
-	Version   int
+	Version          int    `json:"version"`
+	TerraformVersion string `json:"terraform_version,omitempty"`
+	Serial           int    `json:"serial"`
+	Lineage          string `json:"lineage,omitempty"`
+	// Outputs are the root module outputs, which are kept as they are.
+	Outputs      map[string]json.RawMessage `json:"outputs,omitempty"`
+	Resources    []Resource                 `json:"resources"`
+	CheckResults json.RawMessage            `json:"check_results,omitempty"`
 }
 
 type Resource struct {
-	Module    string
-	Mode      string
-	Type      string
-	Name      string
-	Provider  string
-	Instances []Instance
+	Module    string     `json:"module,omitempty"`
+	Mode      string     `json:"mode"`
+	Type      string     `json:"type"`
+	Name      string     `json:"name"`
+	Each      string     `json:"each,omitempty"`
+	Provider  string     `json:"provider"`
+	Instances []Instance `json:"instances"`
 }
 
 type Instance struct {
-	Attributes   map[string]interface{}
-	Dependencies []string
-	IndexKey     interface{} `json:"index_key"`
+	IndexKey interface{} `json:"index_key,omitempty"`
+	// Status is "tainted" for instances that must be replaced.
+	Status string `json:"status,omitempty"`
+	// Deposed is set for objects that a create_before_destroy replacement
+	// failed to destroy. Their key tells them apart from the current object.
+	Deposed       string                 `json:"deposed,omitempty"`
+	SchemaVersion int                    `json:"schema_version"`
+	Attributes    map[string]interface{} `json:"attributes,omitempty"`
+	// AttributesFlat are the attributes of states written by Terraform 0.11
+	// and earlier.
+	AttributesFlat map[string]string `json:"attributes_flat,omitempty"`
+	// SensitiveAttributes are the paths of the attribute values that
+	// Terraform marked as sensitive.
+	SensitiveAttributes   []Path          `json:"sensitive_attributes,omitempty"`
+	IdentitySchemaVersion int             `json:"identity_schema_version,omitempty"`
+	Identity              json.RawMessage `json:"identity,omitempty"`
+	Private               string          `json:"private,omitempty"`
+	Dependencies          []string        `json:"dependencies,omitempty"`
+	CreateBeforeDestroy   bool            `json:"create_before_destroy,omitempty"`
+
+	// rawAttributes are the attributes as they were read. They're written
+	// back as long as Attributes is unchanged, so that numbers keep their
+	// precision.
+	rawAttributes json.RawMessage
+}
+
+func (i *Instance) UnmarshalJSON(b []byte) error {
+	// instance has the fields of Instance without its JSON methods.
+	type instance Instance
+	var v struct {
+		instance
+		Attributes json.RawMessage `json:"attributes"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*i = Instance(v.instance)
+	if len(v.Attributes) == 0 || string(v.Attributes) == "null" {
+		return nil
+	}
+	i.rawAttributes = v.Attributes
+	return json.Unmarshal(v.Attributes, &i.Attributes)
+}
+
+func (i Instance) MarshalJSON() ([]byte, error) {
+	// The fields are listed again to keep the attributes where Terraform
+	// writes them.
+	v := struct {
+		IndexKey              interface{}       `json:"index_key,omitempty"`
+		Status                string            `json:"status,omitempty"`
+		Deposed               string            `json:"deposed,omitempty"`
+		SchemaVersion         int               `json:"schema_version"`
+		Attributes            json.RawMessage   `json:"attributes,omitempty"`
+		AttributesFlat        map[string]string `json:"attributes_flat,omitempty"`
+		SensitiveAttributes   []Path            `json:"sensitive_attributes,omitempty"`
+		IdentitySchemaVersion int               `json:"identity_schema_version,omitempty"`
+		Identity              json.RawMessage   `json:"identity,omitempty"`
+		Private               string            `json:"private,omitempty"`
+		Dependencies          []string          `json:"dependencies,omitempty"`
+		CreateBeforeDestroy   bool              `json:"create_before_destroy,omitempty"`
+	}{
+		IndexKey:              i.IndexKey,
+		Status:                i.Status,
+		Deposed:               i.Deposed,
+		SchemaVersion:         i.SchemaVersion,
+		AttributesFlat:        i.AttributesFlat,
+		SensitiveAttributes:   i.SensitiveAttributes,
+		IdentitySchemaVersion: i.IdentitySchemaVersion,
+		Identity:              i.Identity,
+		Private:               i.Private,
+		Dependencies:          i.Dependencies,
+		CreateBeforeDestroy:   i.CreateBeforeDestroy,
+	}
+	if i.Attributes != nil {
+		var err error
+		if v.Attributes, err = i.attributesJSON(); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(v)
+}
+
+// attributesJSON returns the attributes as they were read, unless they were
+// changed since.
+func (i Instance) attributesJSON() (json.RawMessage, error) {
+	if i.unchanged() {
+		return i.rawAttributes, nil
+	}
+	return json.Marshal(i.Attributes)
+}
+
+// ExactAttributes returns the attributes with their numbers as json.Number,
+// with every digit as they were read. Attributes decodes numbers to float64,
+// which rounds integers above 2^53. Attributes that were changed since they
+// were read are returned as they are.
+func (i Instance) ExactAttributes() map[string]interface{} {
+	if !i.unchanged() {
+		return i.Attributes
+	}
+	d := json.NewDecoder(bytes.NewReader(i.rawAttributes))
+	d.UseNumber()
+	var exact map[string]interface{}
+	if err := d.Decode(&exact); err != nil {
+		return i.Attributes
+	}
+	return exact
+}
+
+// unchanged reports whether the attributes are still the ones that were read.
+func (i Instance) unchanged() bool {
+	if i.rawAttributes == nil {
+		return false
+	}
+	var read map[string]interface{}
+	return json.Unmarshal(i.rawAttributes, &read) == nil && reflect.DeepEqual(read, i.Attributes)
+}
+
+// Tainted reports whether the instance is marked for replacement.
+func (i Instance) Tainted() bool {
+	return i.Status == "tainted"
+}
+
+// Path is the path of a value in the attributes of an instance.
+type Path []PathStep
+
+// PathStep is an attribute name, with type "get_attr", or a list index or map
+// key, with type "index".
+type PathStep struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// String returns the path the way Terraform addresses nested values, e.g.
+// `rule[0].password`. Map keys that aren't identifiers are quoted.
+func (p Path) String() string {
+	var b strings.Builder
+	for _, s := range p {
+		v := s.Value
+		// Index values are typed, e.g. {"value": 0, "type": "number"}.
+		if m, ok := v.(map[string]interface{}); ok {
+			v = m["value"]
+		}
+		switch v := v.(type) {
+		case string:
+			if s.Type == "index" && !IsIdentifier(v) {
+				fmt.Fprintf(&b, "[%q]", v)
+				continue
+			}
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(v)
+		case float64:
+			fmt.Fprintf(&b, "[%s]", strconv.FormatFloat(v, 'f', -1, 64))
+		default:
+			fmt.Fprintf(&b, "[%v]", v)
+		}
+	}
+	return b.String()
+}
+
+var identifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]*$`)
+
+// Quote returns s as an HCL string literal, escaping template sequences. It's
+// how Terraform quotes the string index keys of addresses.
+func Quote(s string) string {
+	q := strconv.Quote(s)
+	q = strings.ReplaceAll(q, "${", "$${")
+	return strings.ReplaceAll(q, "%{", "%%{")
+}
+
+// IsIdentifier reports whether name is an identifier, which attribute names
+// and map keys have to be to be written without quotes.
+func IsIdentifier(name string) bool {
+	return identifier.MatchString(name)
 }
 
 func ParseStateFile(filename string) (V4, error) {
@@ -31,8 +219,37 @@ func ParseStateFile(filename string) (V4, error) {
 		return V4{}, err
 	}
 
+	return Parse(bs)
+}
+
+// Parse decodes the contents of a state file.
+func Parse(bs []byte) (V4, error) {
 	var s V4
-	err = json.Unmarshal(bs, &s)
+	err := json.Unmarshal(bs, &s)
 
 	return s, err
 }
+
+// NewLineage returns a random lineage for a new state, a version 4 UUID like
+// the lineages Terraform creates.
+func NewLineage() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// Marshal encodes a state the way Terraform writes state files.
+func Marshal(s V4) ([]byte, error) {
+	if s.Resources == nil {
+		s.Resources = []Resource{}
+	}
+	bs, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(bs, '\n'), nil
+}
@@ -1,9 +1,14 @@
 package state
 
 import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
 func TestParseStateFile(t *testing.T) {
@@ -103,7 +108,8 @@ func TestParseStateFile(t *testing.T) {
 				Name:     "public-services",
 				Provider: "provider[\"registry.terraform.io/hashicorp/google\"]",
 				Instances: []Instance{{
-					IndexKey: "api",
+					IndexKey:      "api",
+					SchemaVersion: 1,
 					Attributes: map[string]interface{}{
 						"id": "projects/prod/global/backendServices/api",
 					},
@@ -153,9 +159,112 @@ func TestParseStateFile(t *testing.T) {
 			if err != nil {
 				t.Fatalf("failed to parse statefile \"testdata/example.tfstate\": %v", err)
 			}
-			if diff := cmp.Diff(c.want, got); diff != "" {
+			if diff := cmp.Diff(c.want, got, cmpopts.IgnoreUnexported(Instance{})); diff != "" {
 				t.Errorf("ParseStateFile() mismatch (-want +got):\n%s", diff)
 			}
 		})
 	}
 }
+
+func TestPathString(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"attribute", `[{"type": "get_attr", "value": "password"}]`, "password"},
+		{"list index", `[{"type": "get_attr", "value": "rule"}, {"type": "index", "value": {"value": 0, "type": "number"}}, {"type": "get_attr", "value": "secret"}]`, "rule[0].secret"},
+		{"map key", `[{"type": "get_attr", "value": "env"}, {"type": "index", "value": {"value": "DB_PASSWORD", "type": "string"}}]`, "env.DB_PASSWORD"},
+		{"quoted map key", `[{"type": "get_attr", "value": "labels"}, {"type": "index", "value": {"value": "a b", "type": "string"}}]`, `labels["a b"]`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Path
+			if err := json.Unmarshal([]byte(tt.src), &p); err != nil {
+				t.Fatal(err)
+			}
+			if got := p.String(); got != tt.want {
+				t.Errorf("Path.String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuote(t *testing.T) {
+	for in, want := range map[string]string{
+		"plain":         `"plain"`,
+		`say "hi"`:      `"say \"hi\""`,
+		"${var.x}":      `"$${var.x}"`,
+		"%{if x}":       `"%%{if x}"`,
+		"line\nbreak":   `"line\nbreak"`,
+		"$ and % alone": `"$ and % alone"`,
+	} {
+		if got := Quote(in); got != want {
+			t.Errorf("Quote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	want, err := os.ReadFile("testdata/full.tfstate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := Parse(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Marshal(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(want), string(got)); diff != "" {
+		t.Error("Marshal() of a parsed state mismatch (-want, +got):", diff)
+	}
+
+	// Changed attributes are encoded again.
+	st.Resources[0].Instances[1].Attributes["id"] = "renamed"
+	got, err = Marshal(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), `"id": "renamed"`) || !strings.Contains(string(got), `"project_number": 934875098234759823`) {
+		t.Errorf("Marshal() of a changed state = %s, want the new id and the original project_number", got)
+	}
+}
+
+func TestInstanceExactAttributes(t *testing.T) {
+	var inst Instance
+	if err := json.Unmarshal([]byte(`{"attributes": {"id": 9007199254740993, "size": 1.5, "name": "n", "rule": [{"port": 9007199254740993}]}}`), &inst); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"id":   json.Number("9007199254740993"),
+		"size": json.Number("1.5"),
+		"name": "n",
+		"rule": []interface{}{map[string]interface{}{"port": json.Number("9007199254740993")}},
+	}
+	if diff := cmp.Diff(want, inst.ExactAttributes()); diff != "" {
+		t.Error("ExactAttributes() mismatch (-want, +got):", diff)
+	}
+
+	inst.Attributes["id"] = float64(7)
+	if got := inst.ExactAttributes()["id"]; got != float64(7) {
+		t.Errorf("ExactAttributes() of changed attributes has id %#v, want 7", got)
+	}
+}
+
+func TestNewLineage(t *testing.T) {
+	a, err := NewLineage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewLineage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lineage.MatchString(a) || a == b {
+		t.Errorf("NewLineage() = %q, %q, want two different version 4 UUIDs", a, b)
+	}
+}
+
+var lineage = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
@@ -0,0 +1,96 @@
+package transform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/maps"
+
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+)
+
+// Part is a state to merge with others.
+type Part struct {
+	// Name identifies the state in errors, usually by its location.
+	Name  string
+	State state.V4
+	// Module is the module to move the resources of the state into, empty to
+	// keep them where they are.
+	Module string
+}
+
+// DuplicateID is an object that more than one of the merged states manage,
+// found by the import IDs of their resources.
+type DuplicateID struct {
+	Type      string
+	ID        string
+	Addresses []string
+}
+
+func (d DuplicateID) String() string {
+	return fmt.Sprintf("%s %q is managed by %s", d.Type, d.ID, strings.Join(d.Addresses, " and "))
+}
+
+// Merge returns a new state, with its own lineage, that has the resources of
+// every part moved into its module. Outputs aren't merged, Terraform writes
+// them again on the next apply. Merge fails if two resources end up at the
+// same address, and returns the objects more than one part manages.
+func Merge(parts []Part) (state.V4, []DuplicateID, error) {
+	lineage, err := state.NewLineage()
+	if err != nil {
+		return state.V4{}, nil, err
+	}
+	merged := state.V4{Version: 4, Serial: 1, Lineage: lineage}
+
+	owners := map[string]string{}
+	var collisions []string
+	ids := map[string][]string{}
+	for _, p := range parts {
+		if p.State.Version != 4 {
+			return state.V4{}, nil, fmt.Errorf("%s: unsupported state version %d, want 4", p.Name, p.State.Version)
+		}
+		if merged.TerraformVersion == "" {
+			merged.TerraformVersion = p.State.TerraformVersion
+		}
+		moved, err := MoveModule(p.State.Resources, "", p.Module)
+		if err != nil {
+			return state.V4{}, nil, fmt.Errorf("%s: %w", p.Name, err)
+		}
+		for _, r := range moved {
+			a := Address(r)
+			if other, ok := owners[a]; ok {
+				collisions = append(collisions, fmt.Sprintf("%s is in %s and %s", a, other, p.Name))
+				continue
+			}
+			owners[a] = p.Name
+		}
+		merged.Resources = append(merged.Resources, moved...)
+
+		rm := resources.FromState(state.V4{Resources: moved}, "")
+		addresses := maps.Keys(rm)
+		sort.Strings(addresses)
+		for _, a := range addresses {
+			r := rm[a]
+			key := r.Type + " " + r.ImportableID()
+			ids[key] = append(ids[key], a)
+		}
+	}
+	if len(collisions) > 0 {
+		return state.V4{}, nil, fmt.Errorf("resources at the same address, move the states into different modules: %s", strings.Join(collisions, "; "))
+	}
+
+	var duplicates []DuplicateID
+	for key, addresses := range ids {
+		if len(addresses) < 2 {
+			continue
+		}
+		typ, id, _ := strings.Cut(key, " ")
+		duplicates = append(duplicates, DuplicateID{Type: typ, ID: id, Addresses: addresses})
+	}
+	sort.Slice(duplicates, func(i, j int) bool {
+		return duplicates[i].Addresses[0] < duplicates[j].Addresses[0]
+	})
+	return merged, duplicates, nil
+}
@@ -0,0 +1,73 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+)
+
+func stack(lineage string, resources ...state.Resource) state.V4 {
+	return state.V4{Version: 4, Serial: 9, Lineage: lineage, TerraformVersion: "1.9.5", Resources: resources}
+}
+
+func bucket(name, id string, deps ...string) state.Resource {
+	return state.Resource{
+		Mode:      "managed",
+		Type:      "google_storage_bucket",
+		Name:      name,
+		Provider:  `provider["registry.terraform.io/hashicorp/google"]`,
+		Instances: []state.Instance{{Attributes: map[string]interface{}{"id": id}, Dependencies: deps}},
+	}
+}
+
+func TestMerge(t *testing.T) {
+	parts := []Part{
+		{Name: "a.tfstate", State: stack("a", bucket("logs", "a-logs"), bucket("assets", "a-assets", "google_storage_bucket.logs")), Module: "module.a"},
+		{Name: "b.tfstate", State: stack("b", bucket("logs", "b-logs"), bucket("shared", "a-assets")), Module: "module.b"},
+		{Name: "c.tfstate", State: stack("c", bucket("extra", "c-extra"))},
+	}
+	got, duplicates, err := Merge(parts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"module.a.google_storage_bucket.logs",
+		"module.a.google_storage_bucket.assets",
+		"module.b.google_storage_bucket.logs",
+		"module.b.google_storage_bucket.shared",
+		"google_storage_bucket.extra",
+	}
+	if diff := cmp.Diff(want, addresses(got)); diff != "" {
+		t.Error("Merge() resources mismatch (-want, +got):", diff)
+	}
+	if deps := got.Resources[1].Instances[0].Dependencies; len(deps) != 1 || deps[0] != "module.a.google_storage_bucket.logs" {
+		t.Errorf("Merge() dependencies = %v, want them moved into module.a", deps)
+	}
+	if got.Serial != 1 || got.Lineage == "" || got.Lineage == "a" || got.TerraformVersion != "1.9.5" {
+		t.Errorf("Merge() = serial %d, lineage %q, version %q, want serial 1, a new lineage and version 1.9.5", got.Serial, got.Lineage, got.TerraformVersion)
+	}
+
+	wantDuplicates := []DuplicateID{{
+		Type:      "google_storage_bucket",
+		ID:        "a-assets",
+		Addresses: []string{"module.a.google_storage_bucket.assets", "module.b.google_storage_bucket.shared"},
+	}}
+	if diff := cmp.Diff(wantDuplicates, duplicates); diff != "" {
+		t.Error("Merge() duplicates mismatch (-want, +got):", diff)
+	}
+}
+
+func TestMergeCollision(t *testing.T) {
+	parts := []Part{
+		{Name: "a.tfstate", State: stack("a", bucket("logs", "a-logs"))},
+		{Name: "b.tfstate", State: stack("b", bucket("logs", "b-logs"))},
+	}
+	_, _, err := Merge(parts)
+	if err == nil || !strings.Contains(err.Error(), "google_storage_bucket.logs is in a.tfstate and b.tfstate") {
+		t.Errorf("Merge() = %v, want an address collision", err)
+	}
+}
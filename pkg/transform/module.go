@@ -0,0 +1,74 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+)
+
+// MoveModule returns a copy of the resources with those in the module from,
+// and the modules it calls, moved to the module to, along with the
+// dependencies on them and the provider configurations of those modules. An
+// empty from or to is the root module, so that moving from the root prefixes
+// every resource, and moving to the root strips the module. Provider
+// configurations of the root module stay there, as modules inherit them.
+func MoveModule(resources []state.Resource, from, to string) ([]state.Resource, error) {
+	moved := make([]state.Resource, len(resources))
+	for i, r := range resources {
+		moved[i] = r
+		if !underModule(r.Module, from) {
+			continue
+		}
+		moved[i].Module = movePrefix(r.Module, from, to)
+
+		p, err := state.ParseProviderConfig(r.Provider)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", Address(r), err)
+		}
+		if p.Module != "" && underModule(p.Module, from) {
+			p.Module = movePrefix(p.Module, from, to)
+			moved[i].Provider = p.String()
+		}
+	}
+
+	for i, r := range moved {
+		moved[i].Instances = make([]state.Instance, len(r.Instances))
+		for j, inst := range r.Instances {
+			if len(inst.Dependencies) > 0 {
+				deps := make([]string, len(inst.Dependencies))
+				for k, d := range inst.Dependencies {
+					deps[k] = d
+					if underModule(d, from) {
+						deps[k] = movePrefix(d, from, to)
+					}
+				}
+				inst.Dependencies = deps
+			}
+			moved[i].Instances[j] = inst
+		}
+	}
+	if err := checkUnique(moved); err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
+
+// underModule reports whether address is in module, or a module it calls. An
+// empty module is the root, which every address is in. Unlike inModule, the
+// module has to include the index key of its instance, if any.
+func underModule(address, module string) bool {
+	return module == "" || address == module || strings.HasPrefix(address, module+".")
+}
+
+// movePrefix replaces the module from at the start of address with to.
+func movePrefix(address, from, to string) string {
+	rest := strings.TrimPrefix(strings.TrimPrefix(address, from), ".")
+	switch {
+	case to == "":
+		return rest
+	case rest == "":
+		return to
+	}
+	return to + "." + rest
+}
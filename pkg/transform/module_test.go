@@ -0,0 +1,105 @@
+package transform
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+)
+
+func TestMoveModule(t *testing.T) {
+	resources := []state.Resource{{
+		Mode:     "managed",
+		Type:     "t",
+		Name:     "root",
+		Provider: `provider["registry.terraform.io/hashicorp/t"]`,
+		Instances: []state.Instance{{
+			Dependencies: []string{"module.net.t.vpc"},
+		}},
+	}, {
+		Module:   "module.net",
+		Mode:     "managed",
+		Type:     "t",
+		Name:     "vpc",
+		Provider: `provider["registry.terraform.io/hashicorp/t"]`,
+		Instances: []state.Instance{{
+			Dependencies: []string{"module.net.data.t.zones", "t.root"},
+		}},
+	}, {
+		Module:   "module.net.module.subnets[0]",
+		Mode:     "managed",
+		Type:     "t",
+		Name:     "subnet",
+		Provider: `module.net.provider["registry.terraform.io/hashicorp/t"].eu`,
+		Instances: []state.Instance{{
+			Dependencies: []string{"module.net.t.vpc"},
+		}},
+	}, {
+		Module:   "module.network",
+		Mode:     "managed",
+		Type:     "t",
+		Name:     "other",
+		Provider: `module.network.provider["registry.terraform.io/hashicorp/t"]`,
+	}}
+
+	for _, tt := range []struct {
+		name     string
+		from, to string
+		want     []string
+	}{{
+		name: "to root",
+		from: "module.net",
+		want: []string{
+			`t.root provider["registry.terraform.io/hashicorp/t"] [t.vpc]`,
+			`t.vpc provider["registry.terraform.io/hashicorp/t"] [data.t.zones t.root]`,
+			`module.subnets[0].t.subnet provider["registry.terraform.io/hashicorp/t"].eu [t.vpc]`,
+			`module.network.t.other module.network.provider["registry.terraform.io/hashicorp/t"] []`,
+		},
+	}, {
+		name: "from root",
+		to:   "module.app",
+		want: []string{
+			`module.app.t.root provider["registry.terraform.io/hashicorp/t"] [module.app.module.net.t.vpc]`,
+			`module.app.module.net.t.vpc provider["registry.terraform.io/hashicorp/t"] [module.app.module.net.data.t.zones module.app.t.root]`,
+			`module.app.module.net.module.subnets[0].t.subnet module.app.module.net.provider["registry.terraform.io/hashicorp/t"].eu [module.app.module.net.t.vpc]`,
+			`module.app.module.network.t.other module.app.module.network.provider["registry.terraform.io/hashicorp/t"] []`,
+		},
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MoveModule(resources, tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var summary []string
+			for _, r := range got {
+				var deps []string
+				for _, inst := range r.Instances {
+					deps = append(deps, inst.Dependencies...)
+				}
+				summary = append(summary, fmt.Sprint(Address(r), " ", r.Provider, " ", deps))
+			}
+			if diff := cmp.Diff(tt.want, summary); diff != "" {
+				t.Error("MoveModule() return mismatch (-want, +got):", diff)
+			}
+		})
+	}
+
+	if _, err := MoveModule(resources, "module.net", ""); err != nil {
+		t.Fatal(err)
+	}
+	if r := resources[1]; r.Module != "module.net" || r.Instances[0].Dependencies[0] != "module.net.data.t.zones" {
+		t.Errorf("MoveModule() changed its argument to %+v", r)
+	}
+}
+
+func TestMoveModuleCollision(t *testing.T) {
+	resources := []state.Resource{
+		{Mode: "managed", Type: "t", Name: "a", Provider: `provider["registry.terraform.io/hashicorp/t"]`},
+		{Module: "module.m", Mode: "managed", Type: "t", Name: "a", Provider: `provider["registry.terraform.io/hashicorp/t"]`},
+	}
+	if _, err := MoveModule(resources, "module.m", ""); err == nil {
+		t.Error("MoveModule() succeeded, want an error for two resources at t.a")
+	}
+}
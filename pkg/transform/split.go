@@ -0,0 +1,176 @@
+package transform
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+)
+
+// Selection chooses the resources to split off a state. A resource is
+// selected when it matches every criterion that is set.
+type Selection struct {
+	// Module selects the resources in the module and the modules it calls.
+	Module string
+	// Patterns select the resources whose address, without an index key,
+	// matches one of them, as matched by path.Match.
+	Patterns []string
+	// Provider selects the resources whose provider contains it.
+	Provider string
+	// Dependencies adds every resource the selected resources depend on,
+	// directly or not.
+	Dependencies bool
+}
+
+func (s Selection) matches(r state.Resource) (bool, error) {
+	if s.Provider != "" && !strings.Contains(r.Provider, s.Provider) || !inModule(r.Module, s.Module) {
+		return false, nil
+	}
+	if len(s.Patterns) == 0 {
+		return true, nil
+	}
+	for _, p := range s.Patterns {
+		ok, err := path.Match(p, Address(r))
+		if err != nil {
+			return false, fmt.Errorf("pattern %q: %w", p, err)
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SplitResult is a state split in two.
+type SplitResult struct {
+	// Moved has the selected resources, moved to their new module. It's a
+	// new state, with its own lineage.
+	Moved state.V4
+	// Remaining has the other resources, with the next serial and the same
+	// lineage as the original.
+	Remaining state.V4
+	// Selected are the addresses of the selected resources in the original
+	// state, in order.
+	Selected []string
+	// Warnings are the dependencies between resources that the split
+	// separates.
+	Warnings []string
+}
+
+// Split moves the selected resources out of the state, into a new state in
+// which the module root is the root module. Selected resources outside of
+// root keep their address.
+func Split(st state.V4, sel Selection, root string) (SplitResult, error) {
+	selected := map[string]bool{}
+	byAddress := make(map[string]state.Resource, len(st.Resources))
+	for _, r := range st.Resources {
+		byAddress[Address(r)] = r
+		ok, err := sel.matches(r)
+		if err != nil {
+			return SplitResult{}, err
+		}
+		if ok {
+			selected[Address(r)] = true
+		}
+	}
+	if len(selected) == 0 {
+		return SplitResult{}, fmt.Errorf("no resources match the selection")
+	}
+	if sel.Dependencies {
+		queue := make([]string, 0, len(selected))
+		for a := range selected {
+			queue = append(queue, a)
+		}
+		for len(queue) > 0 {
+			a := queue[0]
+			queue = queue[1:]
+			for _, d := range dependencies(byAddress[a], byAddress) {
+				if !selected[d] {
+					selected[d] = true
+					queue = append(queue, d)
+				}
+			}
+		}
+	}
+
+	var moved, remaining []state.Resource
+	var warnings []string
+	for _, r := range st.Resources {
+		a := Address(r)
+		if selected[a] {
+			moved = append(moved, r)
+		} else {
+			remaining = append(remaining, r)
+		}
+		for _, d := range dependencies(r, byAddress) {
+			if selected[a] == selected[d] {
+				continue
+			}
+			if selected[a] {
+				warnings = append(warnings, fmt.Sprintf("%s moves, but depends on %s, which stays", a, d))
+			} else {
+				warnings = append(warnings, fmt.Sprintf("%s stays, but depends on %s, which moves", a, d))
+			}
+		}
+	}
+	moved, err := MoveModule(moved, root, "")
+	if err != nil {
+		return SplitResult{}, err
+	}
+
+	lineage, err := state.NewLineage()
+	if err != nil {
+		return SplitResult{}, err
+	}
+	result := SplitResult{
+		Moved: state.V4{
+			Version:          4,
+			TerraformVersion: st.TerraformVersion,
+			Serial:           1,
+			Lineage:          lineage,
+			Resources:        moved,
+		},
+		Remaining: st,
+		Warnings:  warnings,
+	}
+	result.Remaining.Serial++
+	result.Remaining.Resources = remaining
+	for a := range selected {
+		result.Selected = append(result.Selected, a)
+	}
+	sort.Strings(result.Selected)
+	return result, nil
+}
+
+// dependencies returns the addresses of the resources in known that r
+// depends on, without the index keys of dependencies on single instances.
+func dependencies(r state.Resource, known map[string]state.Resource) []string {
+	seen := map[string]bool{}
+	var deps []string
+	for _, inst := range r.Instances {
+		for _, d := range inst.Dependencies {
+			a, ok := resolve(d, known)
+			if ok && !seen[a] {
+				seen[a] = true
+				deps = append(deps, a)
+			}
+		}
+	}
+	return deps
+}
+
+// resolve returns the address in known that the dependency d refers to.
+func resolve(d string, known map[string]state.Resource) (string, bool) {
+	for {
+		if _, ok := known[d]; ok {
+			return d, true
+		}
+		i := strings.LastIndex(d, "[")
+		if i < 0 || !strings.HasSuffix(d, "]") {
+			return "", false
+		}
+		d = d[:i]
+	}
+}
@@ -0,0 +1,96 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+)
+
+func monolith() state.V4 {
+	resource := func(module, typ, name string, deps ...string) state.Resource {
+		return state.Resource{
+			Module:    module,
+			Mode:      "managed",
+			Type:      typ,
+			Name:      name,
+			Provider:  `provider["registry.terraform.io/hashicorp/google"]`,
+			Instances: []state.Instance{{Attributes: map[string]interface{}{"id": name}, Dependencies: deps}},
+		}
+	}
+	return state.V4{
+		Version: 4,
+		Serial:  3,
+		Lineage: "monolith",
+		Resources: []state.Resource{
+			resource("", "google_project", "main"),
+			resource("module.network", "google_compute_network", "vpc", "google_project.main"),
+			resource("module.network", "google_compute_subnetwork", "subnet", "module.network.google_compute_network.vpc"),
+			resource("module.app", "google_cloud_run_v2_service", "api", `module.network.google_compute_subnetwork.subnet["a"]`),
+		},
+	}
+}
+
+func TestSplit(t *testing.T) {
+	for _, tt := range []struct {
+		name          string
+		sel           Selection
+		root          string
+		wantMoved     []string
+		wantRemaining []string
+		wantWarnings  []string
+	}{{
+		name:          "module to root",
+		sel:           Selection{Module: "module.network"},
+		root:          "module.network",
+		wantMoved:     []string{"google_compute_network.vpc", "google_compute_subnetwork.subnet"},
+		wantRemaining: []string{"google_project.main", "module.app.google_cloud_run_v2_service.api"},
+		wantWarnings: []string{
+			"module.network.google_compute_network.vpc moves, but depends on google_project.main, which stays",
+			`module.app.google_cloud_run_v2_service.api stays, but depends on module.network.google_compute_subnetwork.subnet, which moves`,
+		},
+	}, {
+		name:          "pattern with dependencies",
+		sel:           Selection{Patterns: []string{"module.app.*"}, Dependencies: true},
+		wantMoved:     []string{"google_project.main", "module.network.google_compute_network.vpc", "module.network.google_compute_subnetwork.subnet", "module.app.google_cloud_run_v2_service.api"},
+		wantRemaining: nil,
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			st := monolith()
+			got, err := Split(st, tt.sel, tt.root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.wantMoved, addresses(got.Moved)); diff != "" {
+				t.Error("Split() moved mismatch (-want, +got):", diff)
+			}
+			if diff := cmp.Diff(tt.wantRemaining, addresses(got.Remaining)); diff != "" {
+				t.Error("Split() remaining mismatch (-want, +got):", diff)
+			}
+			if diff := cmp.Diff(tt.wantWarnings, got.Warnings); diff != "" {
+				t.Error("Split() warnings mismatch (-want, +got):", diff)
+			}
+			if got.Remaining.Serial != 4 || got.Remaining.Lineage != "monolith" {
+				t.Errorf("Split() remaining serial %d, lineage %s, want 4, monolith", got.Remaining.Serial, got.Remaining.Lineage)
+			}
+			if got.Moved.Serial != 1 || got.Moved.Lineage == "" || got.Moved.Lineage == "monolith" {
+				t.Errorf("Split() moved serial %d, lineage %s, want 1 and a new lineage", got.Moved.Serial, got.Moved.Lineage)
+			}
+		})
+	}
+}
+
+func TestSplitNoMatch(t *testing.T) {
+	if _, err := Split(monolith(), Selection{Module: "module.db"}, ""); err == nil {
+		t.Error("Split() succeeded, want an error when nothing is selected")
+	}
+}
+
+func addresses(st state.V4) []string {
+	var as []string
+	for _, r := range st.Resources {
+		as = append(as, Address(r))
+	}
+	return as
+}
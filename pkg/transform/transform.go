@@ -0,0 +1,262 @@
+// Package transform rewrites a state offline, for migrations that only change
+// the provider address or schema metadata of resources rather than needing a
+// real re-import.
+package transform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+)
+
+// Transformation describes the changes to make to a state. Resource types are
+// those of the original state, before Types renames them.
+type Transformation struct {
+	// Providers maps provider source addresses to their replacements, e.g.
+	// hashicorp/google to registry.terraform.io/acme/google.
+	Providers map[string]string
+	// Types maps resource types to their new names.
+	Types map[string]string
+	// RenameAttributes maps resource types to the top-level attributes to
+	// rename, from their old to their new name.
+	RenameAttributes map[string]map[string]string
+	// DropAttributes maps resource types to the top-level attributes to
+	// remove.
+	DropAttributes map[string][]string
+	// ResetSchemaVersion are the resource types whose schema_version is reset
+	// to 0, with "*" matching every type.
+	ResetSchemaVersion []string
+
+	// Module limits the transformation to the resources in the module and the
+	// modules it calls, e.g. module.api. Without an index key it matches every
+	// instance of the module.
+	Module string
+	// Alias limits provider replacements to the provider configurations with
+	// the alias. The alias, and the module a provider is configured in, are
+	// kept either way.
+	Alias string
+}
+
+// inModule reports whether a resource in module is in m or a module it calls.
+func inModule(module, m string) bool {
+	return m == "" || module == m || strings.HasPrefix(module, m+".") || strings.HasPrefix(module, m+"[")
+}
+
+// Change is a change made to a resource.
+type Change struct {
+	Address     string
+	Description string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s", c.Address, c.Description)
+}
+
+// Apply returns a copy of the state with the transformation applied to the
+// resources whose provider contains provider, along with the changes made in
+// address order. The copy has the next serial and the same lineage, so that it
+// can replace the state with `terraform state push`.
+func Apply(st state.V4, t Transformation, provider string) (state.V4, []Change, error) {
+	providers := make(map[string]string, len(t.Providers))
+	for from, to := range t.Providers {
+		providers[state.NormalizeSource(from)] = state.NormalizeSource(to)
+	}
+
+	out := st
+	out.Serial++
+	out.Resources = make([]state.Resource, len(st.Resources))
+	var changes []Change
+	// moved are the addresses of the resources that Types renamed.
+	moved := map[string]string{}
+	for i, r := range st.Resources {
+		out.Resources[i] = r
+		if provider != "" && !strings.Contains(r.Provider, provider) || !inModule(r.Module, t.Module) {
+			continue
+		}
+		address := Address(r)
+		change := func(format string, args ...interface{}) {
+			changes = append(changes, Change{Address: address, Description: fmt.Sprintf(format, args...)})
+		}
+
+		if len(providers) > 0 {
+			p, err := state.ParseProviderConfig(r.Provider)
+			if err != nil {
+				return state.V4{}, nil, fmt.Errorf("%s: %w", address, err)
+			}
+			if to, ok := providers[p.Source]; ok && (t.Alias == "" || p.Alias == t.Alias) {
+				change("provider %s -> %s", p.Source, to)
+				p.Source = to
+				out.Resources[i].Provider = p.String()
+			}
+		}
+
+		if to, ok := t.Types[r.Type]; ok {
+			change("type %s -> %s", r.Type, to)
+			out.Resources[i].Type = to
+			moved[address] = Address(out.Resources[i])
+		}
+
+		renames, drops := t.RenameAttributes[r.Type], t.DropAttributes[r.Type]
+		reset := resetsSchema(t.ResetSchemaVersion, r.Type)
+		if len(renames) == 0 && len(drops) == 0 && !reset {
+			continue
+		}
+		out.Resources[i].Instances = make([]state.Instance, len(r.Instances))
+		wasReset := false
+		for j, inst := range r.Instances {
+			inst, err := transformInstance(inst, renames, drops)
+			if err != nil {
+				return state.V4{}, nil, fmt.Errorf("%s: %w", address, err)
+			}
+			if reset && inst.SchemaVersion != 0 {
+				inst.SchemaVersion = 0
+				wasReset = true
+			}
+			out.Resources[i].Instances[j] = inst
+		}
+		for _, from := range resources.SortedKeys(renames) {
+			change("attribute %s -> %s", from, renames[from])
+		}
+		for _, a := range drops {
+			change("dropped attribute %s", a)
+		}
+		if wasReset {
+			change("schema_version reset to 0")
+		}
+	}
+
+	if len(moved) > 0 {
+		for i := range out.Resources {
+			out.Resources[i].Instances = moveDependencies(out.Resources[i].Instances, moved)
+		}
+	}
+	if err := checkUnique(out.Resources); err != nil {
+		return state.V4{}, nil, err
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Address < changes[j].Address
+	})
+	return out, changes, nil
+}
+
+// Address is the address of a resource in state, without an index key, as
+// dependencies refer to it.
+func Address(r state.Resource) string {
+	a := r.Type + "." + r.Name
+	if r.Mode == "data" {
+		a = "data." + a
+	}
+	if r.Module != "" {
+		a = r.Module + "." + a
+	}
+	return a
+}
+
+func resetsSchema(types []string, typ string) bool {
+	for _, t := range types {
+		if t == "*" || t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// transformInstance returns the instance with its attributes renamed and
+// dropped, leaving the original untouched.
+func transformInstance(inst state.Instance, renames map[string]string, drops []string) (state.Instance, error) {
+	if len(renames) == 0 && len(drops) == 0 {
+		return inst, nil
+	}
+	attrs := make(map[string]interface{}, len(inst.Attributes))
+	for k, v := range inst.Attributes {
+		attrs[k] = v
+	}
+	var sensitive []state.Path
+	for _, p := range inst.SensitiveAttributes {
+		sensitive = append(sensitive, append(state.Path{}, p...))
+	}
+
+	for _, from := range resources.SortedKeys(renames) {
+		to := renames[from]
+		v, ok := attrs[from]
+		if !ok {
+			continue
+		}
+		if _, exists := attrs[to]; exists {
+			return state.Instance{}, fmt.Errorf("can't rename attribute %s to %s, which already exists", from, to)
+		}
+		delete(attrs, from)
+		attrs[to] = v
+		for _, p := range sensitive {
+			if len(p) > 0 && p[0].Type == "get_attr" && p[0].Value == from {
+				p[0].Value = to
+			}
+		}
+	}
+	for _, a := range drops {
+		delete(attrs, a)
+		kept := sensitive[:0]
+		for _, p := range sensitive {
+			if len(p) == 0 || p[0].Type != "get_attr" || p[0].Value != a {
+				kept = append(kept, p)
+			}
+		}
+		sensitive = kept
+	}
+
+	if inst.Attributes != nil {
+		inst.Attributes = attrs
+	}
+	if inst.SensitiveAttributes != nil {
+		inst.SensitiveAttributes = sensitive
+	}
+	return inst, nil
+}
+
+// moveDependencies returns the instances with their dependencies on moved
+// resources, or on instances of them, updated.
+func moveDependencies(instances []state.Instance, moved map[string]string) []state.Instance {
+	var out []state.Instance
+	for j, inst := range instances {
+		deps := make([]string, len(inst.Dependencies))
+		changed := false
+		for k, d := range inst.Dependencies {
+			deps[k] = d
+			for from, to := range moved {
+				if d == from || strings.HasPrefix(d, from+"[") {
+					deps[k] = to + strings.TrimPrefix(d, from)
+					changed = true
+					break
+				}
+			}
+		}
+		if !changed {
+			continue
+		}
+		if out == nil {
+			out = append([]state.Instance{}, instances...)
+		}
+		out[j].Dependencies = deps
+	}
+	if out == nil {
+		return instances
+	}
+	return out
+}
+
+// checkUnique returns an error if two resources have the same address, which
+// a type rename can cause.
+func checkUnique(resources []state.Resource) error {
+	seen := make(map[string]bool, len(resources))
+	for _, r := range resources {
+		a := Address(r)
+		if seen[a] {
+			return fmt.Errorf("more than one resource at %s after the transformation", a)
+		}
+		seen[a] = true
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+)
+
+func original() state.V4 {
+	return state.V4{
+		Version: 4,
+		Serial:  7,
+		Lineage: "a1b2c3",
+		Resources: []state.Resource{{
+			Mode:     "managed",
+			Type:     "old_bucket",
+			Name:     "assets",
+			Provider: `provider["registry.terraform.io/hashicorp/old"]`,
+			Instances: []state.Instance{{
+				SchemaVersion: 2,
+				Attributes:    map[string]interface{}{"id": "assets", "bucket_name": "assets", "legacy": true},
+				SensitiveAttributes: []state.Path{
+					{{Type: "get_attr", Value: "bucket_name"}},
+					{{Type: "get_attr", Value: "legacy"}},
+				},
+			}},
+		}, {
+			Module:   "module.app",
+			Mode:     "managed",
+			Type:     "other_thing",
+			Name:     "x",
+			Provider: `module.app.provider["registry.terraform.io/hashicorp/old"].eu`,
+			Instances: []state.Instance{{
+				SchemaVersion: 1,
+				Attributes:    map[string]interface{}{"id": "x"},
+				Dependencies:  []string{"old_bucket.assets", "data.old_bucket.assets"},
+			}},
+		}, {
+			Mode:     "managed",
+			Type:     "old_bucket",
+			Name:     "unrelated",
+			Provider: `provider["registry.terraform.io/hashicorp/random"]`,
+			Instances: []state.Instance{{
+				Attributes: map[string]interface{}{"id": "u"},
+			}},
+		}},
+	}
+}
+
+func TestApply(t *testing.T) {
+	st := original()
+	got, changes, err := Apply(st, Transformation{
+		Providers:          map[string]string{"hashicorp/old": "acme/new"},
+		Types:              map[string]string{"old_bucket": "new_bucket"},
+		RenameAttributes:   map[string]map[string]string{"old_bucket": {"bucket_name": "name"}},
+		DropAttributes:     map[string][]string{"old_bucket": {"legacy"}},
+		ResetSchemaVersion: []string{"*"},
+	}, "hashicorp/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := state.V4{
+		Version: 4,
+		Serial:  8,
+		Lineage: "a1b2c3",
+		Resources: []state.Resource{{
+			Mode:     "managed",
+			Type:     "new_bucket",
+			Name:     "assets",
+			Provider: `provider["registry.terraform.io/acme/new"]`,
+			Instances: []state.Instance{{
+				Attributes:          map[string]interface{}{"id": "assets", "name": "assets"},
+				SensitiveAttributes: []state.Path{{{Type: "get_attr", Value: "name"}}},
+			}},
+		}, {
+			Module:   "module.app",
+			Mode:     "managed",
+			Type:     "other_thing",
+			Name:     "x",
+			Provider: `module.app.provider["registry.terraform.io/acme/new"].eu`,
+			Instances: []state.Instance{{
+				Attributes:   map[string]interface{}{"id": "x"},
+				Dependencies: []string{"new_bucket.assets", "data.old_bucket.assets"},
+			}},
+		}, original().Resources[2]},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreUnexported(state.Instance{})); diff != "" {
+		t.Error("Apply() state mismatch (-want, +got):", diff)
+	}
+	if diff := cmp.Diff(original(), st, cmpopts.IgnoreUnexported(state.Instance{})); diff != "" {
+		t.Error("Apply() changed the original state (-want, +got):", diff)
+	}
+
+	var gotChanges []string
+	for _, c := range changes {
+		gotChanges = append(gotChanges, c.String())
+	}
+	wantChanges := []string{
+		"module.app.other_thing.x: provider registry.terraform.io/hashicorp/old -> registry.terraform.io/acme/new",
+		"module.app.other_thing.x: schema_version reset to 0",
+		"old_bucket.assets: provider registry.terraform.io/hashicorp/old -> registry.terraform.io/acme/new",
+		"old_bucket.assets: type old_bucket -> new_bucket",
+		"old_bucket.assets: attribute bucket_name -> name",
+		"old_bucket.assets: dropped attribute legacy",
+		"old_bucket.assets: schema_version reset to 0",
+	}
+	if diff := cmp.Diff(wantChanges, gotChanges); diff != "" {
+		t.Error("Apply() changes mismatch (-want, +got):", diff)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		t       Transformation
+		wantErr string
+	}{{
+		name: "no collision",
+		t:    Transformation{Types: map[string]string{"old_bucket": "other_thing"}},
+	}, {
+		name:    "rename to existing attribute",
+		t:       Transformation{RenameAttributes: map[string]map[string]string{"old_bucket": {"bucket_name": "id"}}},
+		wantErr: "already exists",
+	}, {
+		name:    "collision after rename",
+		t:       Transformation{Types: map[string]string{"old_bucket": "new_bucket"}},
+		wantErr: "more than one resource at new_bucket.unrelated",
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			st := original()
+			st.Resources = append(st.Resources, state.Resource{Mode: "managed", Type: "new_bucket", Name: "unrelated", Provider: `provider["registry.terraform.io/hashicorp/random"]`})
+			_, _, err := Apply(st, tt.t, "")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Apply() = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Apply() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyScope(t *testing.T) {
+	st := original()
+	st.Resources = append(st.Resources, state.Resource{
+		Module:   "module.app[1].module.db",
+		Mode:     "managed",
+		Type:     "other_thing",
+		Name:     "y",
+		Provider: `module.app[1].provider["registry.terraform.io/hashicorp/old"]`,
+	}, state.Resource{
+		Module:   "module.application",
+		Mode:     "managed",
+		Type:     "other_thing",
+		Name:     "z",
+		Provider: `module.application.provider["registry.terraform.io/hashicorp/old"].eu`,
+	})
+
+	for _, tt := range []struct {
+		name  string
+		scope Transformation
+		want  []string
+	}{{
+		name:  "everywhere",
+		scope: Transformation{},
+		want:  []string{"module.app.other_thing.x", "module.app[1].module.db.other_thing.y", "module.application.other_thing.z", "old_bucket.assets"},
+	}, {
+		name:  "module",
+		scope: Transformation{Module: "module.app"},
+		want:  []string{"module.app.other_thing.x", "module.app[1].module.db.other_thing.y"},
+	}, {
+		name:  "alias",
+		scope: Transformation{Alias: "eu"},
+		want:  []string{"module.app.other_thing.x", "module.application.other_thing.z"},
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := tt.scope
+			tr.Providers = map[string]string{"hashicorp/old": "acme/new"}
+			got, changes, err := Apply(st, tr, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var addresses []string
+			for _, c := range changes {
+				addresses = append(addresses, c.Address)
+			}
+			if diff := cmp.Diff(tt.want, addresses); diff != "" {
+				t.Error("Apply() changed resources mismatch (-want, +got):", diff)
+			}
+			// Every scope includes module.app.other_thing.x.
+			if p := got.Resources[1].Provider; p != `module.app.provider["registry.terraform.io/acme/new"].eu` {
+				t.Errorf("Apply() provider = %s, want the module and alias kept", p)
+			}
+		})
+	}
+}
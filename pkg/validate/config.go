@@ -0,0 +1,148 @@
+package validate
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cmdpdx/tf-state-import/pkg/hcl"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+)
+
+// configured is a resource block, by the address of the block.
+type configured struct {
+	resource hcl.Resource
+	// modules are the calls of the modules the resource is declared in.
+	modules []hcl.ModuleCall
+}
+
+// Config checks the resources that will be imported, rm, against the
+// configuration in root. Every resource must have a matching resource block, or
+// its import fails, and resource blocks without any instance in state, all, are
+// reported as they'd be created instead of imported. Resources that moved
+// blocks move must be imported at their new address. Addresses are compared
+// after rewrite. Resources in modules that aren't local can't be checked.
+func Config(rm, all resources.ResourceMap, root *hcl.Module, rewrite func(string) string) []Problem {
+	blocks := map[string]configured{}
+	remote := map[string]hcl.ModuleCall{}
+	collect(root, "", nil, blocks, remote)
+
+	var problems []Problem
+	for _, prefix := range resources.SortedKeys(remote) {
+		c := remote[prefix]
+		problems = append(problems, Problem{
+			Severity: Warning,
+			Address:  prefix,
+			Message:  fmt.Sprintf("module source %q isn't a local directory, its resources aren't checked", c.Source),
+		})
+	}
+
+	inState := map[string]bool{}
+	for _, a := range resources.SortedKeys(all) {
+		to, _, _ := root.MovedTo(rewrite(a))
+		if addr, err := hcl.ParseAddress(to); err == nil {
+			inState[addr.ConfigAddress()] = true
+		}
+	}
+
+	for _, a := range resources.SortedKeys(rm) {
+		to := rewrite(a)
+		addr, err := hcl.ParseAddress(to)
+		if err != nil {
+			problems = append(problems, Problem{Severity: Error, Address: to, Message: err.Error()})
+			continue
+		}
+		if inRemoteModule(addr, remote) {
+			continue
+		}
+		if moved, b, ok := root.MovedTo(to); ok {
+			problems = append(problems, Problem{
+				Severity: Error,
+				Address:  to,
+				Message:  fmt.Sprintf("is moved to %s by the moved block at %s, import it at its new address with a rewrite", moved, b.Pos),
+			})
+			continue
+		}
+		block, ok := blocks[addr.ConfigAddress()]
+		if !ok {
+			problems = append(problems, Problem{Severity: Error, Address: to, Message: "has no resource block in the configuration"})
+			continue
+		}
+		if msg := mismatch(addr, block); msg != "" {
+			problems = append(problems, Problem{Severity: Error, Address: to, Message: msg})
+		}
+	}
+
+	for _, a := range resources.SortedKeys(blocks) {
+		b := blocks[a]
+		if b.resource.Mode != "managed" || inState[a] {
+			continue
+		}
+		problems = append(problems, Problem{
+			Severity: Warning,
+			Address:  a,
+			Message:  fmt.Sprintf("resource block at %s has no state, it will be created rather than imported", b.resource.Pos),
+		})
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Severity == Error && problems[j].Severity != Error
+	})
+	return problems
+}
+
+// collect adds the resource blocks of m and the modules it calls.
+func collect(m *hcl.Module, prefix string, calls []hcl.ModuleCall, blocks map[string]configured, remote map[string]hcl.ModuleCall) {
+	for _, r := range m.Resources {
+		address := prefix + r.Type + "." + r.Name
+		if r.Mode == "data" {
+			address = prefix + "data." + r.Type + "." + r.Name
+		}
+		blocks[address] = configured{resource: r, modules: calls}
+	}
+	for _, c := range m.Calls {
+		path := prefix + "module." + c.Name
+		if c.Module == nil {
+			remote[path] = c
+			continue
+		}
+		collect(c.Module, path+".", append(append([]hcl.ModuleCall(nil), calls...), c), blocks, remote)
+	}
+}
+
+func inRemoteModule(addr hcl.Address, remote map[string]hcl.ModuleCall) bool {
+	path := ""
+	for _, m := range addr.Modules {
+		path += "module." + m.Name
+		if _, ok := remote[path]; ok {
+			return true
+		}
+		path += "."
+	}
+	return false
+}
+
+// mismatch describes how the index keys of the address don't match the
+// count or for_each of the blocks declaring it, or returns "".
+func mismatch(addr hcl.Address, block configured) string {
+	for i, m := range addr.Modules {
+		c := block.modules[i]
+		if m.Expansion() != c.Expansion {
+			return fmt.Sprintf("module.%s has %s in state, but the module block at %s uses %s", m.Name, describeKey(m), c.Pos, c.Expansion)
+		}
+	}
+	if addr.Resource.Expansion() != block.resource.Expansion {
+		return fmt.Sprintf("has %s in state, but the resource block at %s uses %s", describeKey(addr.Resource), block.resource.Pos, block.resource.Expansion)
+	}
+	return ""
+}
+
+func describeKey(s hcl.Step) string {
+	switch s.Expansion() {
+	case hcl.Count:
+		return "a number key"
+	case hcl.ForEach:
+		return "a string key"
+	default:
+		return "no key"
+	}
+}
@@ -0,0 +1,92 @@
+package validate
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cmdpdx/tf-state-import/pkg/hcl"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+)
+
+func TestConfig(t *testing.T) {
+	root, err := hcl.LoadModule("../hcl/testdata/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	main := filepath.Join("../hcl/testdata/config", "main.tf")
+	events := filepath.Join("../hcl/testdata/config", "events.tf.json")
+
+	rm := resources.ResourceMap{
+		"google_project_iam_member.metrics-writer": {Type: "google_project_iam_member", Name: "metrics-writer"},
+		"google_storage_bucket.logs[0]":            {Type: "google_storage_bucket", Name: "logs", IndexKey: float64(0)},
+		`module.service["api"].google_cloud_run_v2_service.this[0]`: {
+			Module: `module.service["api"]`, Type: "google_cloud_run_v2_service", Name: "this", IndexKey: float64(0),
+		},
+		// Keyed the wrong way.
+		`module.service[0].google_cloud_run_v2_service_iam_member.invoker["allUsers"]`: {
+			Module: "module.service[0]", Type: "google_cloud_run_v2_service_iam_member", Name: "invoker", IndexKey: "allUsers",
+		},
+		`google_service_account.api["x"]`: {Type: "google_service_account", Name: "api", IndexKey: "x"},
+		// Refactored away, and moved by a rewrite.
+		"google_project_iam_member.removed": {Type: "google_project_iam_member", Name: "removed"},
+		"google_project_iam_member.old":     {Type: "google_project_iam_member", Name: "old"},
+		// Declared in JSON.
+		"google_pubsub_topic.events": {Type: "google_pubsub_topic", Name: "events"},
+		// Moved by a moved block.
+		"google_storage_bucket.archive[0]": {Type: "google_storage_bucket", Name: "archive", IndexKey: float64(0)},
+		// Can't be checked.
+		"module.remote.google_compute_network.vpc": {Module: "module.remote", Type: "google_compute_network", Name: "vpc"},
+	}
+	rewrite := func(a string) string {
+		return strings.Replace(a, "google_project_iam_member.old", "google_project_iam_member.metrics-writer", 1)
+	}
+
+	want := []Problem{{
+		Severity: Error,
+		Address:  "google_project_iam_member.removed",
+		Message:  "has no resource block in the configuration",
+	}, {
+		Severity: Error,
+		Address:  `google_service_account.api["x"]`,
+		Message:  "has a string key in state, but the resource block at " + main + ":27 uses neither count nor for_each",
+	}, {
+		Severity: Error,
+		Address:  "google_storage_bucket.archive[0]",
+		Message:  "is moved to google_storage_bucket.logs[0] by the moved block at " + events + ":9, import it at its new address with a rewrite",
+	}, {
+		Severity: Error,
+		Address:  `module.service[0].google_cloud_run_v2_service_iam_member.invoker["allUsers"]`,
+		Message:  "module.service has a number key in state, but the module block at " + main + ":44 uses for_each",
+	}, {
+		Severity: Warning,
+		Address:  "module.remote",
+		Message:  `module source "terraform-google-modules/network/google" isn't a local directory, its resources aren't checked`,
+	}}
+
+	got := Config(rm, rm, root, rewrite)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("Config() return mismatch (-want, +got):", diff)
+	}
+
+	// Against an empty state, every managed resource block is reported.
+	got = Config(resources.ResourceMap{}, resources.ResourceMap{}, root, rewrite)
+	var addresses []string
+	for _, p := range got {
+		addresses = append(addresses, p.Address)
+	}
+	wantAddresses := []string{
+		"module.remote",
+		"google_project_iam_member.metrics-writer",
+		"google_pubsub_topic.events",
+		"google_service_account.api",
+		"google_storage_bucket.logs",
+		"module.service.google_cloud_run_v2_service.this",
+		"module.service.google_cloud_run_v2_service_iam_member.invoker",
+	}
+	if diff := cmp.Diff(wantAddresses, addresses); diff != "" {
+		t.Error("Config() of an empty state mismatch (-want, +got):", diff)
+	}
+}
@@ -0,0 +1,118 @@
+package validate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/maps"
+
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+)
+
+// Severity is how bad a problem is. Errors prevent a migration, warnings are
+// things the user should look at before migrating.
+type Severity string
+
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+)
+
+// Problem is a single issue found in a state file.
+type Problem struct {
+	Severity Severity
+	Address  string
+	Message  string
+}
+
+func (p Problem) String() string {
+	if p.Address == "" {
+		return fmt.Sprintf("%s: %s", p.Severity, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", p.Severity, p.Address, p.Message)
+}
+
+// State checks that the resources in the state matching provider can be
+// migrated, and returns the problems found ordered by severity and address.
+func State(st state.V4, provider string) []Problem {
+	var problems []Problem
+	if st.Version != 4 {
+		problems = append(problems, Problem{Severity: Error, Message: fmt.Sprintf("unsupported state version %d, want 4", st.Version)})
+	}
+
+	rm, skipped := resources.Collect(st, provider)
+	for _, s := range skipped {
+		problems = append(problems, Problem{Severity: Warning, Address: s.Address, Message: s.Reason + ", it will not be imported"})
+	}
+	for _, d := range resources.Deposed(st, provider) {
+		problems = append(problems, Problem{
+			Severity: Warning,
+			Address:  d.Address,
+			Message:  fmt.Sprintf("deposed object %s (id %s) is orphaned by removing the resource from state, destroy it outside of Terraform", d.Key, d.ID),
+		})
+	}
+
+	if _, err := rm.Order(); err != nil {
+		problems = append(problems, Problem{Severity: Error, Message: err.Error()})
+	}
+
+	// Dependencies are checked against every resource, so that dependencies
+	// on resources outside the provider filter aren't reported.
+	all := resources.FromState(st, "")
+	addresses := maps.Keys(rm)
+	sort.Strings(addresses)
+	importIDs := make(map[string]string, len(rm))
+	for _, a := range addresses {
+		r := rm[a]
+		if n, ok := r.NonImportable(); ok {
+			problems = append(problems, Problem{Severity: Warning, Address: a, Message: n.Reason + ", it's left in state unless -non-importable=import"})
+		}
+		if r.Tainted {
+			problems = append(problems, Problem{Severity: Warning, Address: a, Message: "is tainted, see -tainted for whether it's skipped, tainted again after importing, or fails the migration"})
+		}
+		for _, d := range r.Dependencies {
+			if strings.HasPrefix(d, "data.") || exists(all, d) {
+				continue
+			}
+			problems = append(problems, Problem{Severity: Warning, Address: a, Message: fmt.Sprintf("depends on %s, which is not in state", d)})
+		}
+
+		key := r.Type + " " + r.ImportableID()
+		if other, ok := importIDs[key]; ok {
+			problems = append(problems, Problem{Severity: Warning, Address: a, Message: fmt.Sprintf("has the same import ID as %s", other)})
+		} else {
+			importIDs[key] = a
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Severity == Error && problems[j].Severity != Error
+	})
+	return problems
+}
+
+// exists reports whether address, or any instance of the collection at address,
+// is in the map.
+func exists(rm resources.ResourceMap, address string) bool {
+	if _, ok := rm[address]; ok {
+		return true
+	}
+	for a := range rm {
+		if strings.HasPrefix(a, address+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// HasErrors reports whether any of the problems is an error.
+func HasErrors(problems []Problem) bool {
+	for _, p := range problems {
+		if p.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
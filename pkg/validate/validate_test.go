@@ -0,0 +1,113 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+)
+
+func managed(typ, name string, id interface{}, deps ...string) state.Resource {
+	return state.Resource{
+		Mode:     "managed",
+		Type:     typ,
+		Name:     name,
+		Provider: "provider[\"registry.terraform.io/hashicorp/" + typ + "\"]",
+		Instances: []state.Instance{{
+			Attributes:   map[string]interface{}{"id": id},
+			Dependencies: deps,
+		}},
+	}
+}
+
+func TestState(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		state    state.V4
+		provider string
+		want     []Problem
+	}{{
+		name: "valid",
+		state: state.V4{
+			Version: 4,
+			Resources: []state.Resource{
+				managed("a", "one", "1"),
+				managed("a", "two", "2", "a.one", "data.a.ignored"),
+			},
+		},
+	}, {
+		name:  "wrong version",
+		state: state.V4{Version: 3},
+		want: []Problem{
+			{Severity: Error, Message: "unsupported state version 3, want 4"},
+		},
+	}, {
+		name: "cycle, missing dependency, and duplicate import ID",
+		state: state.V4{
+			Version: 4,
+			Resources: []state.Resource{
+				managed("a", "one", "1", "a.two"),
+				managed("a", "two", "2", "a.one", "a.gone"),
+				managed("a", "dup", "1"),
+			},
+		},
+		want: []Problem{
+			{Severity: Error, Message: "cycle detected at: a.one"},
+			{Severity: Warning, Address: "a.one", Message: "has the same import ID as a.dup"},
+			{Severity: Warning, Address: "a.two", Message: "depends on a.gone, which is not in state"},
+		},
+	}, {
+		name: "skipped resources and filtered dependencies",
+		state: state.V4{
+			Version: 4,
+			Resources: []state.Resource{
+				managed("a", "one", "1", "b.other"),
+				managed("a", "boolean", true),
+				managed("b", "other", "2"),
+			},
+		},
+		provider: "hashicorp/a",
+		want: []Problem{
+			{Severity: Warning, Address: "a.boolean", Message: "resource id true isn't a string or a number, it will not be imported"},
+		},
+	}, {
+		name: "tainted and deposed",
+		state: state.V4{
+			Version: 4,
+			Resources: []state.Resource{{
+				Mode: "managed",
+				Type: "a",
+				Name: "r",
+				Instances: []state.Instance{
+					{Status: "tainted", Attributes: map[string]interface{}{"id": "new"}},
+					{Deposed: "0a1b2c3d", Attributes: map[string]interface{}{"id": "old"}},
+				},
+			}},
+		},
+		want: []Problem{
+			{Severity: Warning, Address: "a.r", Message: "deposed object 0a1b2c3d (id old) is orphaned by removing the resource from state, destroy it outside of Terraform"},
+			{Severity: Warning, Address: "a.r", Message: "is tainted, see -tainted for whether it's skipped, tainted again after importing, or fails the migration"},
+		},
+	}, {
+		name: "non-importable types",
+		state: state.V4{
+			Version: 4,
+			Resources: []state.Resource{
+				managed("null_resource", "hook", "1234"),
+				managed("random_password", "db", "none"),
+			},
+		},
+		want: []Problem{
+			{Severity: Warning, Address: "null_resource.hook", Message: "can't be imported, it's left in state unless -non-importable=import"},
+			{Severity: Warning, Address: "random_password.db", Message: "is imported from its result, with length and character settings reset to their defaults, it's left in state unless -non-importable=import"},
+		},
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := State(tt.state, tt.provider)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error("State() return mismatch (-want, +got):", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package verify
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"sort"
+
+	"golang.org/x/exp/maps"
+
+	"github.com/cmdpdx/tf-state-import/pkg/redact"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+)
+
+// DefaultIgnore are attributes that commonly change on re-import without the
+// resource itself changing.
+var DefaultIgnore = []string{"timeouts", "etag"}
+
+// IDChange is a resource whose ID differs between the states.
+type IDChange struct {
+	Address  string
+	Original string
+	New      string
+}
+
+// AttributeChange is an attribute whose value differs between the states. A nil
+// value means the attribute is absent from that state.
+type AttributeChange struct {
+	Address   string
+	Attribute string
+	Original  interface{}
+	New       interface{}
+}
+
+// Report lists the differences between the original state and the state after
+// re-importing.
+type Report struct {
+	// Missing are resources in the original state that were not re-imported.
+	Missing []string
+	// Extra are resources in the new state that were not in the original.
+	Extra            []string
+	IDChanges        []IDChange
+	AttributeChanges []AttributeChange
+}
+
+// OK reports whether nothing was lost or changed.
+func (r Report) OK() bool {
+	return len(r.Missing) == 0 && len(r.IDChanges) == 0 && len(r.AttributeChanges) == 0
+}
+
+// Verify matches resources by address and compares their IDs and attributes.
+// Attributes whose name matches one of the ignore patterns (see path.Match) are
+// not compared. The values of changed attributes are redacted by the policy.
+func Verify(original, imported resources.ResourceMap, ignore []string, policy redact.Policy) Report {
+	var r Report
+
+	addresses := maps.Keys(original)
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		o := original[address]
+		n, ok := imported[address]
+		if !ok {
+			r.Missing = append(r.Missing, address)
+			continue
+		}
+		idChanged := o.ID != n.ID
+		if idChanged {
+			r.IDChanges = append(r.IDChanges, IDChange{Address: address, Original: o.ID, New: n.ID})
+		}
+		for _, c := range compareAttributes(address, o.Attributes, n.Attributes, ignore) {
+			// The ID is usually also the `id` attribute, don't report it twice.
+			if idChanged && c.Attribute == "id" {
+				continue
+			}
+			c.Original = policy.Value(c.Attribute, c.Original, o.Sensitive)
+			c.New = policy.Value(c.Attribute, c.New, n.Sensitive)
+			r.AttributeChanges = append(r.AttributeChanges, c)
+		}
+	}
+
+	for address := range imported {
+		if _, ok := original[address]; !ok {
+			r.Extra = append(r.Extra, address)
+		}
+	}
+	sort.Strings(r.Extra)
+
+	return r
+}
+
+func compareAttributes(address string, original, imported map[string]interface{}, ignore []string) []AttributeChange {
+	keys := maps.Keys(original)
+	for k := range imported {
+		if _, ok := original[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []AttributeChange
+	for _, k := range keys {
+		if ignored(k, ignore) {
+			continue
+		}
+		if !reflect.DeepEqual(original[k], imported[k]) {
+			changes = append(changes, AttributeChange{Address: address, Attribute: k, Original: original[k], New: imported[k]})
+		}
+	}
+	return changes
+}
+
+func ignored(name string, ignore []string) bool {
+	for _, pattern := range ignore {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Write writes a human readable form of the report.
+func (r Report) Write(w io.Writer) error {
+	var err error
+	printf := func(format string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+
+	for _, a := range r.Missing {
+		printf("missing   %s\n", a)
+	}
+	for _, a := range r.Extra {
+		printf("extra     %s\n", a)
+	}
+	for _, c := range r.IDChanges {
+		printf("id        %s: %q -> %q\n", c.Address, c.Original, c.New)
+	}
+	for _, c := range r.AttributeChanges {
+		printf("attribute %s: %s: %#v -> %#v\n", c.Address, c.Attribute, c.Original, c.New)
+	}
+	if r.OK() {
+		printf("ok: all resources were re-imported unchanged\n")
+	} else {
+		printf("%d missing, %d id changes, %d attribute changes\n", len(r.Missing), len(r.IDChanges), len(r.AttributeChanges))
+	}
+	return err
+}
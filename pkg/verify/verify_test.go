@@ -0,0 +1,151 @@
+package verify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cmdpdx/tf-state-import/pkg/redact"
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+)
+
+func TestVerify(t *testing.T) {
+	original := resources.ResourceMap{
+		"t.same": {
+			Type:       "t",
+			Name:       "same",
+			ID:         "same-id",
+			Attributes: map[string]interface{}{"id": "same-id", "etag": "a"},
+		},
+		"t.lost": {
+			Type: "t",
+			Name: "lost",
+			ID:   "lost-id",
+		},
+		"t.changed": {
+			Type: "t",
+			Name: "changed",
+			ID:   "old-id",
+			Attributes: map[string]interface{}{
+				"id":      "old-id",
+				"labels":  map[string]interface{}{"env": "prod"},
+				"dropped": "x",
+			},
+		},
+	}
+	imported := resources.ResourceMap{
+		"t.same": {
+			Type:       "t",
+			Name:       "same",
+			ID:         "same-id",
+			Attributes: map[string]interface{}{"id": "same-id", "etag": "b"},
+		},
+		"t.changed": {
+			Type: "t",
+			Name: "changed",
+			ID:   "new-id",
+			Attributes: map[string]interface{}{
+				"id":     "new-id",
+				"labels": map[string]interface{}{"env": "dev"},
+				"added":  true,
+			},
+		},
+		"t.new": {
+			Type: "t",
+			Name: "new",
+			ID:   "new-id",
+		},
+	}
+
+	for _, tt := range []struct {
+		name   string
+		ignore []string
+		want   Report
+	}{{
+		name:   "default ignore",
+		ignore: DefaultIgnore,
+		want: Report{
+			Missing:   []string{"t.lost"},
+			Extra:     []string{"t.new"},
+			IDChanges: []IDChange{{Address: "t.changed", Original: "old-id", New: "new-id"}},
+			AttributeChanges: []AttributeChange{
+				{Address: "t.changed", Attribute: "added", New: true},
+				{Address: "t.changed", Attribute: "dropped", Original: "x"},
+				{
+					Address:   "t.changed",
+					Attribute: "labels",
+					Original:  map[string]interface{}{"env": "prod"},
+					New:       map[string]interface{}{"env": "dev"},
+				},
+			},
+		},
+	}, {
+		name:   "glob ignore",
+		ignore: []string{"etag", "id", "*ed"},
+		want: Report{
+			Missing:   []string{"t.lost"},
+			Extra:     []string{"t.new"},
+			IDChanges: []IDChange{{Address: "t.changed", Original: "old-id", New: "new-id"}},
+			AttributeChanges: []AttributeChange{{
+				Address:   "t.changed",
+				Attribute: "labels",
+				Original:  map[string]interface{}{"env": "prod"},
+				New:       map[string]interface{}{"env": "dev"},
+			}},
+		},
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Verify(original, imported, tt.ignore, redact.Default)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error("Verify() return mismatch (-want, +got):", diff)
+			}
+		})
+	}
+}
+
+func TestVerifyRedacted(t *testing.T) {
+	original := resources.ResourceMap{"t.x": {ID: "x", Attributes: map[string]interface{}{"token": "a", "value": "1"}, Sensitive: []string{"value"}}}
+	imported := resources.ResourceMap{"t.x": {ID: "x", Attributes: map[string]interface{}{"token": "b", "value": "2"}, Sensitive: []string{"value"}}}
+
+	want := Report{AttributeChanges: []AttributeChange{
+		{Address: "t.x", Attribute: "token", Original: redact.Marker, New: redact.Marker},
+		{Address: "t.x", Attribute: "value", Original: redact.Marker, New: redact.Marker},
+	}}
+	got := Verify(original, imported, nil, redact.Default)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("Verify() return mismatch (-want, +got):", diff)
+	}
+}
+
+func TestReportWrite(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		report Report
+		want   string
+	}{{
+		name: "ok",
+		want: "ok: all resources were re-imported unchanged\n",
+	}, {
+		name: "differences",
+		report: Report{
+			Missing:          []string{"t.lost"},
+			IDChanges:        []IDChange{{Address: "t.changed", Original: "old", New: "new"}},
+			AttributeChanges: []AttributeChange{{Address: "t.changed", Attribute: "name", Original: "a"}},
+		},
+		want: "missing   t.lost\n" +
+			"id        t.changed: \"old\" -> \"new\"\n" +
+			"attribute t.changed: name: \"a\" -> <nil>\n" +
+			"1 missing, 1 id changes, 1 attribute changes\n",
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := tt.report.Write(&b); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, b.String()); diff != "" {
+				t.Error("Write() output mismatch (-want, +got):", diff)
+			}
+		})
+	}
+}
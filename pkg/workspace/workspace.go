@@ -0,0 +1,80 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// Default is the workspace every configuration starts with, its state is
+	// terraform.tfstate in the configuration directory.
+	Default = "default"
+	// StateFile is the name of the state file of every workspace.
+	StateFile = "terraform.tfstate"
+	// Dir is where the local backend keeps the state of the other workspaces,
+	// one directory per workspace.
+	Dir = "terraform.tfstate.d"
+)
+
+// Workspace is a workspace of the local backend.
+type Workspace struct {
+	Name      string
+	StateFile string
+}
+
+// Discover returns the workspaces with a state file in dir, the default
+// workspace first and the others ordered by name.
+func Discover(dir string) ([]Workspace, error) {
+	var ws []Workspace
+	if exists(filepath.Join(dir, StateFile)) {
+		ws = append(ws, Workspace{Name: Default, StateFile: filepath.Join(dir, StateFile)})
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, Dir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ws, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && exists(filepath.Join(dir, Dir, e.Name(), StateFile)) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ws = append(ws, Workspace{Name: name, StateFile: filepath.Join(dir, Dir, name, StateFile)})
+	}
+	return ws, nil
+}
+
+// Find returns the workspace called name in dir.
+func Find(dir, name string) (Workspace, error) {
+	if name == "" || name != filepath.Base(name) {
+		return Workspace{}, fmt.Errorf("invalid workspace name %q", name)
+	}
+	w := Workspace{Name: name, StateFile: filepath.Join(dir, Dir, name, StateFile)}
+	if name == Default {
+		w.StateFile = filepath.Join(dir, StateFile)
+	}
+	if !exists(w.StateFile) {
+		return Workspace{}, fmt.Errorf("workspace %q has no state, expected %s", name, w.StateFile)
+	}
+	return w, nil
+}
+
+// SelectCommand returns the command that makes w the current workspace.
+func (w Workspace) SelectCommand() string {
+	return fmt.Sprintf("terraform workspace select '%s'", w.Name)
+}
+
+func exists(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && !fi.IsDir()
+}
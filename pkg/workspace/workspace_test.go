@@ -0,0 +1,86 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeState(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`{"version": 4}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	dir := t.TempDir()
+	writeState(t, filepath.Join(dir, "terraform.tfstate"))
+	writeState(t, filepath.Join(dir, "terraform.tfstate.d", "staging", "terraform.tfstate"))
+	writeState(t, filepath.Join(dir, "terraform.tfstate.d", "prod", "terraform.tfstate"))
+	// A workspace that was created but never applied has no state.
+	if err := os.MkdirAll(filepath.Join(dir, "terraform.tfstate.d", "empty"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Discover(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Workspace{
+		{Name: "default", StateFile: filepath.Join(dir, "terraform.tfstate")},
+		{Name: "prod", StateFile: filepath.Join(dir, "terraform.tfstate.d", "prod", "terraform.tfstate")},
+		{Name: "staging", StateFile: filepath.Join(dir, "terraform.tfstate.d", "staging", "terraform.tfstate")},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("Discover() return mismatch (-want, +got):", diff)
+	}
+
+	got, err = Discover(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Discover() of an empty directory = %v", got)
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	writeState(t, filepath.Join(dir, "terraform.tfstate"))
+	writeState(t, filepath.Join(dir, "terraform.tfstate.d", "prod", "terraform.tfstate"))
+
+	for _, tt := range []struct {
+		name    string
+		want    Workspace
+		wantErr bool
+	}{
+		{name: "default", want: Workspace{Name: "default", StateFile: filepath.Join(dir, "terraform.tfstate")}},
+		{name: "prod", want: Workspace{Name: "prod", StateFile: filepath.Join(dir, "terraform.tfstate.d", "prod", "terraform.tfstate")}},
+		{name: "missing", wantErr: true},
+		{name: "../prod", wantErr: true},
+		{name: "", wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Find(dir, tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Find() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error("Find() return mismatch (-want, +got):", diff)
+			}
+		})
+	}
+}
+
+func TestSelectCommand(t *testing.T) {
+	got := Workspace{Name: "prod"}.SelectCommand()
+	if want := "terraform workspace select 'prod'"; got != want {
+		t.Errorf("SelectCommand() = %q, want %q", got, want)
+	}
+}
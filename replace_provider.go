@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+	"github.com/cmdpdx/tf-state-import/pkg/transform"
+)
+
+// replaceProviderCommand is an offline `terraform state replace-provider`.
+func replaceProviderCommand(args []string) error {
+	fs := newFlagSet("replace-provider", "[flags] FROM TO", "Write a copy of the state file with the provider source address FROM replaced by TO, like\n'terraform state replace-provider' but without a backend. Aliases and the modules\nproviders are configured in are kept.")
+	var sf stateFlags
+	sf.register(fs)
+	out := fs.String("out", "", "File to write the new state to.")
+	force := fs.Bool("force", false, "Overwrite -out if it exists.")
+	dryRun := fs.Bool("dry-run", false, "Only print the resources that would change, without writing -out.")
+	module := fs.String("module", "", "Only replace the provider of the resources in this module and the modules it calls, e.g. 'module.api'.")
+	alias := fs.String("alias", "", "Only replace the provider configurations with this alias.")
+	if err := sf.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return usageErrorf(fs, "expected FROM and TO provider source addresses, got %d arguments", fs.NArg())
+	}
+	if *out == "" && !*dryRun {
+		return usageErrorf(fs, "-out is required")
+	}
+	from, to := fs.Arg(0), fs.Arg(1)
+
+	loaded, err := sf.load()
+	if err != nil {
+		return err
+	}
+	replaced, changes, err := transform.Apply(loaded.state, transform.Transformation{
+		Providers: map[string]string{from: to},
+		Module:    *module,
+		Alias:     *alias,
+	}, sf.provider)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return fmt.Errorf("no resources use provider %s", state.NormalizeSource(from))
+	}
+
+	if *dryRun {
+		// Apply keeps the order of the resources.
+		for i, r := range loaded.state.Resources {
+			if p := replaced.Resources[i].Provider; p != r.Provider {
+				fmt.Printf("%s: %s -> %s\n", transform.Address(r), r.Provider, p)
+			}
+		}
+		fmt.Printf("%d resources would change\n", len(changes))
+		return nil
+	}
+	if err := writeState(*out, replaced, *force); err != nil {
+		return err
+	}
+	log.Printf("replaced the provider of %d resources, push %s with 'terraform state push'", len(changes), *out)
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/cmdpdx/tf-state-import/pkg/transform"
+)
+
+// rewriteCommand rewrites the state offline, for migrations that don't need
+// resources to be removed and imported again.
+func rewriteCommand(args []string) error {
+	fs := newFlagSet("rewrite", "[flags] -out FILE", "Write a copy of the state file with provider addresses, resource types, attributes or\nschema versions changed, for migrations that don't need a real re-import. The copy has\nthe next serial and the same lineage, ready for 'terraform state push'.")
+	var sf stateFlags
+	sf.register(fs)
+	out := fs.String("out", "", "File to write the rewritten state to.")
+	force := fs.Bool("force", false, "Overwrite -out if it exists.")
+	dryRun := fs.Bool("dry-run", false, "Only log the changes, without writing -out.")
+	providers := pairsFlag{}
+	fs.Var(providers, "replace-provider", "Replace a provider source address, as FROM=TO, e.g. 'hashicorp/google=acme/google'. Can be repeated.")
+	types := pairsFlag{}
+	fs.Var(types, "rename-type", "Rename a resource type, as FROM=TO. Can be repeated.")
+	renames := pairsFlag{}
+	fs.Var(renames, "rename-attribute", "Rename a top-level attribute of a resource type, as TYPE.FROM=TO. Can be repeated.")
+	var drops listFlag
+	fs.Var(&drops, "drop-attribute", "Remove a top-level attribute of a resource type, as TYPE.ATTRIBUTE. Can be repeated.")
+	var resets listFlag
+	fs.Var(&resets, "reset-schema-version", "Reset the schema_version of a resource type to 0, or of every type with '*'. Can be repeated.")
+	if err := sf.parse(fs, args); err != nil {
+		return err
+	}
+	if *out == "" && !*dryRun {
+		return usageErrorf(fs, "-out is required")
+	}
+
+	t := transform.Transformation{
+		Providers:          providers,
+		Types:              types,
+		RenameAttributes:   map[string]map[string]string{},
+		DropAttributes:     map[string][]string{},
+		ResetSchemaVersion: resets,
+	}
+	for from, to := range renames {
+		typ, attr, ok := strings.Cut(from, ".")
+		if !ok {
+			return usageErrorf(fs, "-rename-attribute: expected TYPE.FROM=TO, got %q", from+"="+to)
+		}
+		if t.RenameAttributes[typ] == nil {
+			t.RenameAttributes[typ] = map[string]string{}
+		}
+		t.RenameAttributes[typ][attr] = to
+	}
+	for _, d := range drops {
+		typ, attr, ok := strings.Cut(d, ".")
+		if !ok {
+			return usageErrorf(fs, "-drop-attribute: expected TYPE.ATTRIBUTE, got %q", d)
+		}
+		t.DropAttributes[typ] = append(t.DropAttributes[typ], attr)
+	}
+
+	loaded, err := sf.load()
+	if err != nil {
+		return err
+	}
+	rewritten, changes, err := transform.Apply(loaded.state, t, sf.provider)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return errors.New("no resources match the changes")
+	}
+	for _, c := range changes {
+		log.Println(c)
+	}
+	if *dryRun {
+		return nil
+	}
+	if err := writeState(*out, rewritten, *force); err != nil {
+		return err
+	}
+	log.Printf("wrote %s (serial %d), push it with 'terraform state push %s'", *out, rewritten.Serial, *out)
+	return nil
+}
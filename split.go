@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+	"github.com/cmdpdx/tf-state-import/pkg/transform"
+)
+
+// splitCommand moves resources out of a state into a new state.
+func splitCommand(args []string) error {
+	fs := newFlagSet("split", "[flags]", "Move the selected resources out of the state file into a new state file, for breaking up\na stack. Writes the new state to -out and the rest of the original to -remaining, or\nprints the 'terraform state rm' and 'terraform import' statements that do the same with -plan.")
+	var sf stateFlags
+	sf.register(fs)
+	module := fs.String("module", "", "Select the resources in this module and the modules it calls, e.g. 'module.network'.")
+	var patterns listFlag
+	fs.Var(&patterns, "match", "Select the resources whose address, without an index key, matches this glob, e.g. 'module.app.google_*'. Can be repeated.")
+	withDependencies := fs.Bool("with-dependencies", false, "Also select every resource the selected resources depend on, directly or not.")
+	root := fs.String("root", "", "Module whose resources are at the root of the new state, e.g. 'module.network'. Selected resources outside of it keep their address.")
+	out := fs.String("out", "", "File to write the new state to.")
+	remaining := fs.String("remaining", "", "File to write the original state without the selected resources to, with the next serial.")
+	plan := fs.Bool("plan", false, "Print the 'terraform state rm' statements for the original stack and the 'terraform import' statements for the new stack.")
+	force := fs.Bool("force", false, "Overwrite -out and -remaining if they exist.")
+	if err := sf.parse(fs, args); err != nil {
+		return err
+	}
+	if *module == "" && len(patterns) == 0 {
+		return usageErrorf(fs, "select resources with -module or -match")
+	}
+	if *out == "" && *remaining == "" && !*plan {
+		return usageErrorf(fs, "expected -out, -remaining or -plan")
+	}
+
+	loaded, err := sf.load()
+	if err != nil {
+		return err
+	}
+	result, err := transform.Split(loaded.state, transform.Selection{
+		Module:       *module,
+		Patterns:     patterns,
+		Provider:     sf.provider,
+		Dependencies: *withDependencies,
+	}, *root)
+	if err != nil {
+		return err
+	}
+	for _, w := range result.Warnings {
+		log.Println(w)
+	}
+	log.Printf("selected %d resources", len(result.Selected))
+
+	if *out != "" {
+		if err := writeState(*out, result.Moved, *force); err != nil {
+			return err
+		}
+		log.Printf("wrote the new state to %s", *out)
+	}
+	if *remaining != "" {
+		if err := writeState(*remaining, result.Remaining, *force); err != nil {
+			return err
+		}
+		log.Printf("wrote the remaining state to %s (serial %d)", *remaining, result.Remaining.Serial)
+	}
+	if *plan {
+		return splitPlan(loaded, result.Selected, *root, sf.instructions())
+	}
+	return nil
+}
+
+// splitPlan prints the statements that remove the selected resources from the
+// original stack and import them into the new one.
+func splitPlan(loaded loadedState, selected []string, root string, instructions bool) error {
+	isSelected := make(map[string]bool, len(selected))
+	for _, a := range selected {
+		isSelected[a] = true
+	}
+	rm := resources.ResourceMap{}
+	for a, r := range loaded.resources {
+		if isSelected[r.CollectionAddress()] {
+			rm[a] = r
+		}
+	}
+	var excluded []resources.Excluded
+	for _, e := range loaded.excluded {
+		collection := e.Address
+		if i := strings.LastIndex(collection, "["); i > 0 && strings.HasSuffix(collection, "]") {
+			collection = collection[:i]
+		}
+		if isSelected[collection] {
+			excluded = append(excluded, e)
+		}
+	}
+	ordered, err := rm.Order()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("# In the original stack:")
+	for i := len(ordered) - 1; i >= 0; i-- {
+		fmt.Printf("terraform state rm '%s'\n", ordered[i].Address())
+	}
+	fmt.Println("# In the new stack:")
+	return output(os.Stdout, ordered, generateOptions{
+		format: "command",
+		rewrite: func(a string) string {
+			if root != "" && strings.HasPrefix(a, root+".") {
+				return strings.TrimPrefix(a, root+".")
+			}
+			return a
+		},
+		excluded:     excluded,
+		instructions: instructions,
+	})
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+)
+
+func TestSplitPlan(t *testing.T) {
+	loaded := loadedState{resources: resources.ResourceMap{
+		"module.net.t.vpc":         {Module: "module.net", Type: "t", Name: "vpc", ID: "vpc"},
+		`module.net.t.subnet["a"]`: {Module: "module.net", Type: "t", Name: "subnet", ID: "subnet-a", IndexKey: "a", Dependencies: []string{"module.net.t.vpc"}},
+		"t.app":                    {Type: "t", Name: "app", ID: "app", Dependencies: []string{"module.net.t.vpc"}},
+	}}
+
+	for _, tt := range []struct {
+		name string
+		root string
+		want string
+	}{{
+		name: "keep addresses",
+		want: `# In the original stack:
+terraform state rm 'module.net.t.subnet["a"]'
+terraform state rm 'module.net.t.vpc'
+# In the new stack:
+terraform import 'module.net.t.vpc' vpc
+terraform import 'module.net.t.subnet["a"]' subnet-a
+`,
+	}, {
+		name: "root",
+		root: "module.net",
+		want: `# In the original stack:
+terraform state rm 'module.net.t.subnet["a"]'
+terraform state rm 'module.net.t.vpc'
+# In the new stack:
+terraform import 't.vpc' vpc
+terraform import 't.subnet["a"]' subnet-a
+`,
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := stdout(t, func() error {
+				return splitPlan(loaded, []string{"module.net.t.vpc", "module.net.t.subnet"}, tt.root, false)
+			})
+			if err != nil {
+				t.Fatalf("splitPlan() = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("splitPlan() mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/cmdpdx/tf-state-import/pkg/validate"
+)
+
+func validateCommand(args []string) error {
+	fs := newFlagSet("validate", "[flags]", "Check that the state file can be migrated: a supported state version, no dependency\ncycles, and no resources that will be left behind.")
+	var sf stateFlags
+	sf.register(fs)
+	checkConfigDir := fs.String("check-config", "", "Also check the resources against the Terraform configuration in this directory, including local modules: every resource needs a matching resource block.")
+	strict := fs.Bool("strict", false, "Fail on warnings as well as errors.")
+	if err := sf.parse(fs, args); err != nil {
+		return err
+	}
+
+	loaded, err := sf.load()
+	if err != nil {
+		return err
+	}
+	problems := validate.State(loaded.state, sf.provider)
+	if *checkConfigDir != "" {
+		configured, err := configProblems(*checkConfigDir, loaded, sf.config.RewriteAddress)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, configured...)
+		sort.SliceStable(problems, func(i, j int) bool {
+			return problems[i].Severity == validate.Error && problems[j].Severity != validate.Error
+		})
+	}
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+
+	switch {
+	case validate.HasErrors(problems):
+		return errors.New("state is not valid")
+	case *strict && len(problems) > 0:
+		return errors.New("state has warnings")
+	}
+	fmt.Printf("ok: %d resources\n", len(loaded.resources))
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/cmdpdx/tf-state-import/pkg/resources"
+	"github.com/cmdpdx/tf-state-import/pkg/state"
+	"github.com/cmdpdx/tf-state-import/pkg/verify"
+)
+
+// verifyCommand compares the state from before a migration to the state after
+// it, and exits non-zero if anything was lost or changed.
+func verifyCommand(args []string) error {
+	fs := newFlagSet("verify", "[flags]", "Compare the state from before a migration to the state after it, matching resources by\naddress, and fail if any resource is missing or changed.")
+	var cf configFlags
+	cf.register(fs)
+	original := fs.String("original", "", "State file from before the migration, e.g. the copy written to -rollback-dir.")
+	imported := fs.String("new", "terraform.tfstate", "State file after re-importing.")
+	provider := fs.String("provider", "", "Filter resources by the given provider string, including partial matches. If empty, all resources will be compared.")
+	ignore := fs.String("ignore", strings.Join(verify.DefaultIgnore, ","), "Comma separated attribute names to ignore when comparing. Supports glob patterns such as 'effective_*'.")
+	var rf redactFlags
+	rf.register(fs)
+	if err := cf.parse(fs, args); err != nil {
+		return err
+	}
+	if *original == "" {
+		return usageErrorf(fs, "-original is required")
+	}
+
+	_, before, err := state.Read(context.Background(), *original)
+	if err != nil {
+		return err
+	}
+	_, after, err := state.Read(context.Background(), *imported)
+	if err != nil {
+		return err
+	}
+
+	var patterns []string
+	if *ignore != "" {
+		patterns = strings.Split(*ignore, ",")
+	}
+	report := verify.Verify(resources.FromState(before, *provider), resources.FromState(after, *provider), patterns, rf.policy(cf.config))
+	if err := report.Write(os.Stdout); err != nil {
+		return err
+	}
+	if !report.OK() {
+		return errors.New("states differ")
+	}
+	return nil
+}